@@ -0,0 +1,495 @@
+package keyring
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/agentplexus/omnivault/vault"
+	zkeyring "github.com/zalando/go-keyring"
+)
+
+// Audit event outcomes.
+const (
+	AuditOK       = "ok"
+	AuditNotFound = "not_found"
+	AuditDenied   = "denied"
+	AuditConflict = "conflict"
+	AuditInvalid  = "invalid"
+	AuditError    = "error"
+)
+
+// auditMaxLine is the longest event VerifyAuditLog accepts.
+const auditMaxLine = 1 << 20
+
+// auditHeadPrefix starts the reserved keys under which a provider records
+// the heads of audit logs, followed by the log's absolute path.
+const auditHeadPrefix = "__omnivault_audit__/"
+
+// ErrAuditTampered is returned by Provider.VerifyAuditLog, by OpenAuditLog,
+// and by the first operation recorded in a reopened log, when an audit log
+// has been modified or truncated.
+var ErrAuditTampered = errors.New("audit log tampered")
+
+// AuditLogError describes where an audit log fails verification. It
+// matches ErrAuditTampered.
+type AuditLogError struct {
+	// Line is the 1-based line number of the first bad event.
+	Line int
+
+	// Reason describes what is wrong with it.
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *AuditLogError) Error() string {
+	return fmt.Sprintf("%v: line %d: %s", ErrAuditTampered, e.Line, e.Reason)
+}
+
+// Is reports whether the error matches the target.
+func (e *AuditLogError) Is(target error) bool {
+	return target == ErrAuditTampered
+}
+
+// AuditEvent is a single line of an audit log. It records who performed an
+// operation on which secret, and how it turned out, but never the secret
+// itself.
+type AuditEvent struct {
+	// Seq numbers the events of a log from 1, without gaps.
+	Seq uint64 `json:"seq"`
+
+	// Time is when the operation finished, in UTC.
+	Time time.Time `json:"time"`
+
+	// Op is the operation, as in Request.Op.
+	Op string `json:"op"`
+
+	// Path is the secret, or prefix, the operation acted on.
+	Path string `json:"path,omitempty"`
+
+	// Target is the destination of Rename, Copy and MovePrefix.
+	Target string `json:"target,omitempty"`
+
+	// Paths lists the secrets changed by a Txn.
+	Paths []string `json:"paths,omitempty"`
+
+	// Outcome is AuditOK, AuditNotFound, AuditDenied, AuditConflict,
+	// AuditInvalid or AuditError.
+	Outcome string `json:"outcome"`
+
+	// PID, UID and Executable identify the process. UID is -1 on Windows.
+	PID        int    `json:"pid"`
+	UID        int    `json:"uid"`
+	Executable string `json:"exe,omitempty"`
+
+	// Caller is the identity attached to the operation's context with
+	// WithCaller, if any.
+	Caller string `json:"caller,omitempty"`
+
+	// Prev is the Hash of the previous event, or "" for the first.
+	Prev string `json:"prev"`
+
+	// Hash is the hex SHA-256 of the event's JSON encoding without Hash.
+	Hash string `json:"hash,omitempty"`
+}
+
+// AuditHead identifies the last event of an audit log. An AuditLog records
+// its head in the keyring after every event, so that
+// Provider.VerifyAuditLog can tell a log that lost events from its end from
+// a shorter one.
+type AuditHead struct {
+	// Seq is the sequence number of the last event, or 0 for an empty log.
+	Seq uint64
+
+	// Hash is the hash of the last event.
+	Hash string
+}
+
+// callerKey is the context key of the caller identity.
+type callerKey struct{}
+
+// WithCaller returns a context carrying the identity of the user or
+// service on whose behalf operations are performed, for the audit log.
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// callerFrom returns the identity attached to ctx by WithCaller.
+func callerFrom(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
+// AuditLog appends hash-chained JSON-lines events to a file. Install it with
+// Config.Middleware; put it first so that operations rejected by later
+// middleware are recorded too.
+//
+// The provider performing the first operation that passes through the log
+// becomes its owner: it checks the log against the head recorded in its
+// keyring, and records the new head after every event, like its own
+// writes, with Config.DefaultTimeout and Config.Retry.
+//
+// If an event, or its head, can't be written, the operation it describes
+// fails, and so does every later one: access is never granted without a
+// record of it. An operation that changed a secret before the failure
+// still returns the audit error, even though its change was applied. Only
+// one process may write to a log at a time.
+type AuditLog struct {
+	mu    sync.Mutex
+	file  *os.File
+	path  string    // absolute
+	owner *Provider // records the head; nil until the first operation
+	head  AuditHead
+	err   error // set once writing fails
+
+	pid int
+	uid int
+	exe string
+}
+
+// OpenAuditLog opens the audit log at path for appending, creating it if
+// needed. The hash chain of an existing log is verified first, and the log
+// is not appended to if it has been tampered with. The first operation
+// passing through the log also checks it against its recorded head.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(abs, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	head, err := verifyAuditLog(file, nil)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	exe, _ := os.Executable()
+	return &AuditLog{file: file, path: abs, head: head, pid: os.Getpid(), uid: os.Getuid(), exe: exe}, nil
+}
+
+// Head returns the last event written to the log.
+func (a *AuditLog) Head() AuditHead {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.head
+}
+
+// Close closes the log file. Operations audited by the log fail afterwards.
+func (a *AuditLog) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.err == nil {
+		a.err = os.ErrClosed
+	}
+	return a.file.Close()
+}
+
+// Middleware returns the middleware that records every operation passing
+// through it.
+func (a *AuditLog) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) (*Response, error) {
+			if err := a.failed(); err != nil {
+				return nil, vault.NewVaultError(req.Op, req.Path, "keyring", fmt.Errorf("audit log: %w", err))
+			}
+
+			resp, err := next(ctx, req)
+			if werr := a.record(ctx, req, err); werr != nil {
+				if resp != nil && resp.Secure != nil {
+					resp.Secure.Destroy()
+				}
+				return nil, errors.Join(err, vault.NewVaultError(req.Op, req.Path, "keyring", fmt.Errorf("audit log: %w", werr)))
+			}
+			return resp, err
+		}
+	}
+}
+
+// failed returns the error that stopped the log, if any.
+func (a *AuditLog) failed() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.err
+}
+
+// record appends the event for req and its result.
+func (a *AuditLog) record(ctx context.Context, req *Request, err error) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.err != nil {
+		return a.err
+	}
+	if a.owner == nil {
+		if err := a.bind(ctx); err != nil {
+			a.err = err
+			return err
+		}
+	}
+
+	event := AuditEvent{
+		Seq:        a.head.Seq + 1,
+		Time:       time.Now().UTC(),
+		Op:         req.Op,
+		Path:       req.Path,
+		Target:     req.Target,
+		Paths:      req.Paths,
+		Outcome:    auditOutcome(err),
+		PID:        a.pid,
+		UID:        a.uid,
+		Executable: a.exe,
+		Caller:     callerFrom(ctx),
+		Prev:       a.head.Hash,
+	}
+	line, err := sealAuditEvent(&event)
+	if err == nil {
+		_, err = a.file.Write(line)
+	}
+	head := AuditHead{Seq: event.Seq, Hash: event.Hash}
+	if err == nil {
+		err = a.owner.storeAuditHead(ctx, a.path, head)
+	}
+	if err != nil {
+		a.err = err
+		return err
+	}
+	a.head = head
+	return nil
+}
+
+// bind makes the provider performing the operation in ctx the owner of the
+// log, after checking the log against the head recorded in its keyring.
+func (a *AuditLog) bind(ctx context.Context) error {
+	p := providerFrom(ctx)
+	if p == nil {
+		return errors.New("not called by a provider")
+	}
+	file, err := os.Open(a.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	ctx, cancel := p.detach(ctx)
+	defer cancel()
+	head, err := p.verifyAuditLog(ctx, file, a.path)
+	if err != nil {
+		return err
+	}
+	if head != a.head {
+		return &AuditLogError{Line: int(a.head.Seq) + 1, Reason: "log changed since it was opened"}
+	}
+	a.owner = p
+	return nil
+}
+
+// auditOutcome categorizes the result of an operation.
+func auditOutcome(err error) string {
+	switch {
+	case err == nil:
+		return AuditOK
+	case errors.Is(err, vault.ErrSecretNotFound):
+		return AuditNotFound
+	case errors.Is(err, vault.ErrAccessDenied):
+		return AuditDenied
+	case errors.Is(err, ErrPreconditionFailed), errors.Is(err, vault.ErrAlreadyExists):
+		return AuditConflict
+	case errors.Is(err, vault.ErrInvalidPath):
+		return AuditInvalid
+	default:
+		return AuditError
+	}
+}
+
+// sealAuditEvent sets the event's hash and returns its line in the log.
+func sealAuditEvent(event *AuditEvent) ([]byte, error) {
+	event.Hash = ""
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	event.Hash = hex.EncodeToString(sum[:])
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// VerifyAuditLog checks that the audit log at path is an unbroken hash
+// chain starting from its first event, that it reaches the head recorded
+// for it in p's keyring by the AuditLog p owns, and returns its last event.
+// It returns an *AuditLogError matching ErrAuditTampered if any event was
+// modified, removed, reordered or inserted, or the log was cut short,
+// including by whole events or by being emptied.
+func (p *Provider) VerifyAuditLog(ctx context.Context, path string) (AuditHead, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return AuditHead{}, vault.NewVaultError("VerifyAuditLog", path, p.Name(), vault.ErrClosed)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return AuditHead{}, err
+	}
+	file, err := os.Open(abs)
+	if err != nil {
+		return AuditHead{}, err
+	}
+	defer file.Close()
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	return p.verifyAuditLog(ctx, file, abs)
+}
+
+// RemoveAuditLog deletes the audit log at path and the head recorded for it
+// in p's keyring, so that a new log can be started there. Verify and copy a
+// log before removing it to rotate it; a copy can't be checked for lost
+// events, since its head is recorded for the original path only.
+func (p *Provider) RemoveAuditLog(ctx context.Context, path string) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return vault.NewVaultError("RemoveAuditLog", path, p.Name(), vault.ErrClosed)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(abs); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	if err := p.remove(ctx, auditHeadPrefix+abs); err != nil && !errors.Is(err, zkeyring.ErrNotFound) {
+		return vault.NewVaultError("RemoveAuditLog", path, p.Name(), fmt.Errorf("audit log head: %w", err))
+	}
+	return nil
+}
+
+// loadAuditHead returns the head recorded for the log at path, or nil if
+// none is.
+func (p *Provider) loadAuditHead(ctx context.Context, path string) (*AuditHead, error) {
+	key := auditHeadPrefix + path
+	value, err := p.read(ctx, key)
+	if errors.Is(err, zkeyring.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("audit log head: %w", err)
+	}
+	data, err := p.openRecord(key, value)
+	if err != nil {
+		return nil, fmt.Errorf("audit log head: %w", err)
+	}
+	var head AuditHead
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, fmt.Errorf("audit log head: %w", err)
+	}
+	return &head, nil
+}
+
+// storeAuditHead records head for the log at path. It runs on a context
+// detached from ctx, so that a caller giving up after its operation was
+// recorded can't stop the log.
+func (p *Provider) storeAuditHead(ctx context.Context, path string, head AuditHead) error {
+	data, err := json.Marshal(head)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := p.detach(ctx)
+	defer cancel()
+	key := auditHeadPrefix + path
+	if err := p.write(ctx, key, p.sealRecord(key, data)); err != nil {
+		return fmt.Errorf("audit log head: %w", err)
+	}
+	return nil
+}
+
+// verifyAuditLog verifies the log at path, read from r, against the head
+// recorded for it. The log may be one event past it, if the process writing
+// it stopped before it could record the head of its last event.
+func (p *Provider) verifyAuditLog(ctx context.Context, r io.Reader, path string) (AuditHead, error) {
+	want, err := p.loadAuditHead(ctx, path)
+	if err != nil {
+		return AuditHead{}, err
+	}
+	head, err := verifyAuditLog(r, want)
+	switch {
+	case err != nil:
+		return head, err
+	case want == nil && head.Seq > 0:
+		return head, &AuditLogError{Line: 1, Reason: "no head recorded for the log"}
+	case want != nil && head.Seq < want.Seq:
+		return head, &AuditLogError{Line: int(head.Seq) + 1, Reason: fmt.Sprintf("log ends at event %d of %d", head.Seq, want.Seq)}
+	case want != nil && head.Seq > want.Seq+1:
+		return head, &AuditLogError{Line: int(want.Seq) + 2, Reason: fmt.Sprintf("log continues past event %d", want.Seq+1)}
+	}
+	return head, nil
+}
+
+// verifyAuditLog verifies the hash chain of the log read from r, checking
+// that the event numbered want.Seq, if any, has want.Hash.
+func verifyAuditLog(r io.Reader, want *AuditHead) (AuditHead, error) {
+	var head AuditHead
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, auditMaxLine)
+	scanner.Split(scanAuditLines)
+
+	for line := 1; scanner.Scan(); line++ {
+		raw := scanner.Bytes()
+		if len(raw) == 0 || raw[len(raw)-1] != '\n' {
+			return head, &AuditLogError{Line: line, Reason: "incomplete event"}
+		}
+
+		var event AuditEvent
+		if err := json.Unmarshal(raw, &event); err != nil {
+			return head, &AuditLogError{Line: line, Reason: "malformed event"}
+		}
+		hash := event.Hash
+		sealed, err := sealAuditEvent(&event)
+		switch {
+		case err != nil || event.Hash != hash:
+			return head, &AuditLogError{Line: line, Reason: "hash mismatch"}
+		case !bytes.Equal(sealed, raw):
+			return head, &AuditLogError{Line: line, Reason: "event was modified"}
+		case event.Seq != head.Seq+1:
+			return head, &AuditLogError{Line: line, Reason: fmt.Sprintf("sequence %d follows %d", event.Seq, head.Seq)}
+		case event.Prev != head.Hash:
+			return head, &AuditLogError{Line: line, Reason: "chain broken"}
+		case want != nil && event.Seq == want.Seq && event.Hash != want.Hash:
+			return head, &AuditLogError{Line: line, Reason: "event differs from the recorded head"}
+		}
+		head = AuditHead{Seq: event.Seq, Hash: event.Hash}
+	}
+	return head, scanner.Err()
+}
+
+// scanAuditLines splits a log into lines, keeping the newline so that a
+// final line without one can be recognized as cut short.
+func scanAuditLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
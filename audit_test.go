@@ -0,0 +1,339 @@
+package keyring
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+// newAuditedProvider returns a provider recording to a new audit log.
+func newAuditedProvider(t *testing.T) (*Provider, *AuditLog, string) {
+	t.Helper()
+	useBackend(t, newMemoryBackend())
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := OpenAuditLog(path)
+	if err != nil {
+		t.Fatalf("OpenAuditLog() error = %v", err)
+	}
+	t.Cleanup(func() { audit.Close() })
+	p := New(Config{ServiceName: "audit-test", Middleware: []Middleware{audit.Middleware()}})
+	return p, audit, path
+}
+
+// readAuditEvents returns the events in the log at path.
+func readAuditEvents(t *testing.T, path string) []AuditEvent {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []AuditEvent
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var event AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestAuditLogRecordsOperations(t *testing.T) {
+	p, audit, path := newAuditedProvider(t)
+	ctx := WithCaller(context.Background(), "alice@example.com")
+
+	if err := p.Set(ctx, "db/password", &vault.Secret{Value: "hunter2"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if _, err := p.Get(ctx, "db/password"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	p.Get(context.Background(), "missing")
+	p.SetIfNotExists(ctx, "db/password", &vault.Secret{Value: "other"})
+	if err := p.Rename(ctx, "db/password", "db/pass"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if bytes.Contains(data, []byte("hunter2")) || bytes.Contains(data, []byte("other")) {
+		t.Fatal("audit log contains a secret value")
+	}
+
+	events := readAuditEvents(t, path)
+	want := []struct{ op, path, outcome, caller string }{
+		{"Set", "db/password", AuditOK, "alice@example.com"},
+		{"Get", "db/password", AuditOK, "alice@example.com"},
+		{"Get", "missing", AuditNotFound, ""},
+		{"SetIfNotExists", "db/password", AuditConflict, "alice@example.com"},
+		{"Rename", "db/password", AuditOK, "alice@example.com"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.Op != w.op || e.Path != w.path || e.Outcome != w.outcome || e.Caller != w.caller {
+			t.Errorf("event %d = %+v, want %+v", i, e, w)
+		}
+		if e.Seq != uint64(i+1) || e.PID != os.Getpid() || e.UID != os.Getuid() || e.Executable == "" {
+			t.Errorf("event %d = %+v, missing sequence or process identity", i, e)
+		}
+	}
+	if events[4].Target != "db/pass" {
+		t.Errorf("Rename event target = %q, want db/pass", events[4].Target)
+	}
+
+	head, err := p.VerifyAuditLog(ctx, path)
+	if err != nil {
+		t.Fatalf("VerifyAuditLog() error = %v", err)
+	}
+	if head != audit.Head() || head.Seq != 5 {
+		t.Errorf("VerifyAuditLog() = %+v, want %+v", head, audit.Head())
+	}
+}
+
+func TestAuditLogReopen(t *testing.T) {
+	p, audit, path := newAuditedProvider(t)
+	ctx := context.Background()
+	p.Set(ctx, "a", &vault.Secret{Value: "1"})
+	audit.Close()
+
+	reopened, err := OpenAuditLog(path)
+	if err != nil {
+		t.Fatalf("OpenAuditLog() of an existing log error = %v", err)
+	}
+	defer reopened.Close()
+	p = New(Config{ServiceName: "audit-test", Middleware: []Middleware{reopened.Middleware()}})
+	p.Get(ctx, "a")
+
+	if head, err := p.VerifyAuditLog(ctx, path); err != nil || head.Seq != 2 {
+		t.Errorf("VerifyAuditLog() = %+v, %v; want an unbroken chain of 2", head, err)
+	}
+}
+
+func TestAuditLogTampering(t *testing.T) {
+	p, audit, path := newAuditedProvider(t)
+	ctx := context.Background()
+	for _, name := range []string{"a", "b", "c"} {
+		p.Set(ctx, name, &vault.Secret{Value: name})
+	}
+	audit.Close()
+	data, _ := os.ReadFile(path)
+	lines := strings.SplitAfter(string(data), "\n")[:3]
+
+	tests := []struct {
+		name   string
+		log    string
+		line   int
+		reason string
+	}{
+		{"modified path", lines[0] + strings.Replace(lines[1], `"path":"b"`, `"path":"x"`, 1) + lines[2], 2, "hash mismatch"},
+		{"added field", lines[0] + strings.Replace(lines[1], `{`, `{"note":"x",`, 1) + lines[2], 2, "event was modified"},
+		{"removed event", lines[0] + lines[2], 2, "sequence 3 follows 1"},
+		{"reordered", lines[1] + lines[0] + lines[2], 1, "sequence 2 follows 0"},
+		{"removed head", lines[1] + lines[2], 1, "sequence 2 follows 0"},
+		{"cut short", lines[0] + lines[1] + lines[2][:20], 3, "incomplete event"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.log")
+			os.WriteFile(path, []byte(tt.log), 0o600)
+
+			_, err := p.VerifyAuditLog(ctx, path)
+			var logErr *AuditLogError
+			if !errors.Is(err, ErrAuditTampered) || !errors.As(err, &logErr) {
+				t.Fatalf("VerifyAuditLog() error = %v, want ErrAuditTampered", err)
+			}
+			if logErr.Line != tt.line || logErr.Reason != tt.reason {
+				t.Errorf("VerifyAuditLog() error = %v, want line %d: %s", err, tt.line, tt.reason)
+			}
+			if _, err := OpenAuditLog(path); !errors.Is(err, ErrAuditTampered) {
+				t.Errorf("OpenAuditLog() of a tampered log error = %v, want ErrAuditTampered", err)
+			}
+		})
+	}
+}
+
+func TestAuditLogTruncation(t *testing.T) {
+	p, audit, path := newAuditedProvider(t)
+	ctx := context.Background()
+	for _, name := range []string{"a", "b", "c"} {
+		p.Set(ctx, name, &vault.Secret{Value: name})
+	}
+	audit.Close()
+	data, _ := os.ReadFile(path)
+	lines := strings.SplitAfter(string(data), "\n")[:3]
+
+	// Rewriting the last event and resealing it keeps the chain valid.
+	var last AuditEvent
+	json.Unmarshal([]byte(lines[2]), &last)
+	last.Path = "x"
+	resealed, _ := sealAuditEvent(&last)
+
+	tests := []struct {
+		name   string
+		log    string
+		line   int
+		reason string
+	}{
+		{"removed last event", lines[0] + lines[1], 3, "log ends at event 2 of 3"},
+		{"emptied", "", 1, "log ends at event 0 of 3"},
+		{"resealed last event", lines[0] + lines[1] + string(resealed), 3, "event differs from the recorded head"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.WriteFile(path, []byte(tt.log), 0o600)
+
+			_, err := p.VerifyAuditLog(ctx, path)
+			var logErr *AuditLogError
+			if !errors.As(err, &logErr) || logErr.Line != tt.line || logErr.Reason != tt.reason {
+				t.Fatalf("VerifyAuditLog() error = %v, want line %d: %s", err, tt.line, tt.reason)
+			}
+			if err := useAuditLog(path); !errors.Is(err, ErrAuditTampered) {
+				t.Errorf("first operation on a truncated log error = %v, want ErrAuditTampered", err)
+			}
+		})
+	}
+
+	os.Remove(path)
+	if err := useAuditLog(path); !errors.Is(err, ErrAuditTampered) {
+		t.Errorf("first operation on a deleted log error = %v, want ErrAuditTampered", err)
+	}
+	if err := p.RemoveAuditLog(ctx, path); err != nil {
+		t.Fatalf("RemoveAuditLog() error = %v", err)
+	}
+	if err := useAuditLog(path); err != nil {
+		t.Errorf("first operation on a new log after RemoveAuditLog() error = %v", err)
+	}
+}
+
+// useAuditLog opens the audit log at path for a new provider, and returns
+// the error of the first operation recorded in it.
+func useAuditLog(path string) error {
+	audit, err := OpenAuditLog(path)
+	if err != nil {
+		return err
+	}
+	defer audit.Close()
+	p := New(Config{ServiceName: "audit-test", Middleware: []Middleware{audit.Middleware()}})
+	_, err = p.Exists(context.Background(), "a")
+	return err
+}
+
+func TestAuditLogHead(t *testing.T) {
+	b := newMemoryBackend()
+	useBackend(t, b)
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := OpenAuditLog(path)
+	if err != nil {
+		t.Fatalf("OpenAuditLog() error = %v", err)
+	}
+	defer audit.Close()
+	p := New(Config{ServiceName: "audit-test", Middleware: []Middleware{audit.Middleware()}})
+	ctx := context.Background()
+	p.Set(ctx, "a", &vault.Secret{Value: "1"})
+
+	// A writer stopping between an event and its head leaves the log one
+	// event ahead, which still verifies.
+	failed := errors.New("keyring unavailable")
+	b.failWith(func(op, _, user string) error {
+		if op == "Set" && strings.HasPrefix(user, auditHeadPrefix) {
+			return failed
+		}
+		return nil
+	})
+	if err := p.Set(ctx, "b", &vault.Secret{Value: "2"}); !errors.Is(err, failed) {
+		t.Errorf("Set() with an unrecordable head error = %v, want %v", err, failed)
+	}
+	if _, err := p.Get(ctx, "a"); !errors.Is(err, failed) {
+		t.Errorf("Get() after a failed head error = %v, want %v", err, failed)
+	}
+	b.failWith(nil)
+	if head, err := p.VerifyAuditLog(ctx, path); err != nil || head.Seq != 2 {
+		t.Errorf("VerifyAuditLog() = %+v, %v; want a chain of 2", head, err)
+	}
+
+	// A copy has no recorded head.
+	data, _ := os.ReadFile(path)
+	copied := filepath.Join(t.TempDir(), "copy.log")
+	os.WriteFile(copied, data, 0o600)
+	if _, err := p.VerifyAuditLog(ctx, copied); !errors.Is(err, ErrAuditTampered) {
+		t.Errorf("VerifyAuditLog() of a copy error = %v, want ErrAuditTampered", err)
+	}
+}
+
+func TestAuditLogHeadTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	useBackend(t, gatedBackend{newMemoryBackend(), func(op, user string) {
+		if op == "Set" && strings.HasPrefix(user, auditHeadPrefix) {
+			<-release
+		}
+	}})
+	audit, err := OpenAuditLog(filepath.Join(t.TempDir(), "audit.log"))
+	if err != nil {
+		t.Fatalf("OpenAuditLog() error = %v", err)
+	}
+	defer audit.Close()
+	p := New(Config{ServiceName: "audit-test", DefaultTimeout: 50 * time.Millisecond, Middleware: []Middleware{audit.Middleware()}})
+
+	start := time.Now()
+	_, err = p.Exists(context.Background(), "a")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Exists() with a stalled head write error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Exists() took %v, want it bounded by DefaultTimeout", elapsed)
+	}
+}
+
+func TestAuditLogHeadUsesProviderConfig(t *testing.T) {
+	b := newMemoryBackend()
+	useBackend(t, b)
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := OpenAuditLog(path)
+	if err != nil {
+		t.Fatalf("OpenAuditLog() error = %v", err)
+	}
+	defer audit.Close()
+	p := New(Config{ServiceName: "audit-test", PathKey: NewPathKey(), Middleware: []Middleware{audit.Middleware()}})
+	ctx := context.Background()
+	p.Set(ctx, "a", &vault.Secret{Value: "1"})
+
+	b.mu.Lock()
+	for key := range b.store["audit-test"] {
+		if strings.Contains(key, "audit") || strings.Contains(key, path) {
+			t.Errorf("audit log head stored under a plain key %q", key)
+		}
+	}
+	b.mu.Unlock()
+	if head, err := p.VerifyAuditLog(ctx, path); err != nil || head.Seq != 1 {
+		t.Errorf("VerifyAuditLog() = %+v, %v; want a chain of 1", head, err)
+	}
+	if err := p.Set(ctx, auditHeadPrefix+"audit.log", &vault.Secret{Value: "{}"}); !errors.Is(err, vault.ErrInvalidPath) {
+		t.Errorf("Set() of an audit head key error = %v, want ErrInvalidPath", err)
+	}
+}
+
+func TestAuditLogFailsClosed(t *testing.T) {
+	p, audit, _ := newAuditedProvider(t)
+	ctx := context.Background()
+	if err := p.Set(ctx, "a", &vault.Secret{Value: "1"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	audit.Close()
+
+	if secret, err := p.Get(ctx, "a"); err == nil || secret != nil {
+		t.Errorf("Get() with a closed audit log = %v, %v; want an error", secret, err)
+	}
+}
@@ -0,0 +1,134 @@
+package keyring
+
+import (
+	"context"
+
+	zkeyring "github.com/zalando/go-keyring"
+)
+
+// backend is the storage interface the provider uses to reach an OS keyring.
+// It mirrors the go-keyring API so the OS implementation is a thin adapter.
+type backend interface {
+	// Get returns the value stored for user under service.
+	// It returns zkeyring.ErrNotFound if no value is stored.
+	Get(service, user string) (string, error)
+
+	// Set stores value for user under service, replacing any existing value.
+	Set(service, user, value string) error
+
+	// Delete removes the value stored for user under service.
+	// It returns zkeyring.ErrNotFound if no value is stored.
+	Delete(service, user string) error
+}
+
+// newBackend returns the backend used by providers created with New.
+// Tests replace it with an in-memory implementation.
+var newBackend = newOSBackend
+
+// osBackend stores secrets in the OS credential store via go-keyring.
+type osBackend struct{}
+
+func (osBackend) Get(service, user string) (string, error) {
+	return zkeyring.Get(service, user)
+}
+
+func (osBackend) Set(service, user, value string) error {
+	return zkeyring.Set(service, user, value)
+}
+
+func (osBackend) Delete(service, user string) error {
+	return zkeyring.Delete(service, user)
+}
+
+// read returns the value stored at user, retrying transient failures and
+// abandoning the call when ctx is done. Errors are classified by classify.
+func (p *Provider) read(ctx context.Context, user string) (string, error) {
+	var value string
+	err := p.retry(ctx, "Get", user, func() (err error) {
+		value, err = call(ctx, func() (string, error) {
+			return p.backend.Get(p.config.ServiceName, p.backendKey(user))
+		})
+		return err
+	})
+	return value, classify(err)
+}
+
+// write stores value at user, retrying transient failures and abandoning
+// the call when ctx is done. The cached value of user is invalidated before
+// and after the call, so a read racing the write can't leave the old value
+// cached.
+func (p *Provider) write(ctx context.Context, user, value string) error {
+	p.invalidate(user)
+	defer p.invalidate(user)
+	return classify(p.retry(ctx, "Set", user, func() error {
+		_, err := call(ctx, func() (struct{}, error) {
+			return struct{}{}, p.backend.Set(p.config.ServiceName, p.backendKey(user), value)
+		})
+		return err
+	}))
+}
+
+// remove deletes the value stored at user, retrying transient failures and
+// abandoning the call when ctx is done. Like write, it invalidates the
+// cached value of user.
+func (p *Provider) remove(ctx context.Context, user string) error {
+	p.invalidate(user)
+	defer p.invalidate(user)
+	return classify(p.retry(ctx, "Delete", user, func() error {
+		_, err := call(ctx, func() (struct{}, error) {
+			return struct{}{}, p.backend.Delete(p.config.ServiceName, p.backendKey(user))
+		})
+		return err
+	}))
+}
+
+// backendKey returns the key the backend stores user under: user itself, or
+// its HMAC when paths are obfuscated, after the active profile's prefix.
+func (p *Provider) backendKey(user string) string {
+	if p.paths != nil {
+		user = p.paths.key(user)
+	}
+	return p.keyPrefix + user
+}
+
+// call runs fn in its own goroutine and waits for it to return or for ctx to
+// be done, whichever comes first. Backend calls can't be interrupted, so an
+// abandoned call keeps running in the background and may still take effect.
+func call[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := fn()
+		done <- result{value, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// withTimeout applies Config.DefaultTimeout to ctx if it has no deadline.
+func (p *Provider) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || p.config.DefaultTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, p.config.DefaultTimeout)
+}
+
+// detach returns a context for bookkeeping that must finish once a write has
+// been applied, such as index updates and rollbacks: it keeps ctx's values
+// but not its cancellation, and is bounded by Config.DefaultTimeout.
+func (p *Provider) detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	return p.withTimeout(context.WithoutCancel(ctx))
+}
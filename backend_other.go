@@ -0,0 +1,9 @@
+//go:build !linux
+
+package keyring
+
+// newOSBackend returns the go-keyring backend. Config.Collection only
+// applies to the Secret Service.
+func newOSBackend(Config) backend {
+	return osBackend{}
+}
@@ -0,0 +1,89 @@
+package keyring
+
+import (
+	"sync"
+
+	zkeyring "github.com/zalando/go-keyring"
+)
+
+// memoryBackend is a concurrency-safe in-memory backend for tests.
+type memoryBackend struct {
+	mu     sync.Mutex
+	store  map[string]map[string]string
+	calls  map[string]int
+	failFn func(op, service, user string) error
+}
+
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{
+		store: make(map[string]map[string]string),
+		calls: make(map[string]int),
+	}
+}
+
+// useBackend makes providers created by New use b until the test finishes.
+func useBackend(t interface{ Cleanup(func()) }, b backend) {
+	old := newBackend
+	newBackend = func(Config) backend { return b }
+	t.Cleanup(func() { newBackend = old })
+}
+
+// failWith makes operations for which fn returns a non-nil error fail.
+func (m *memoryBackend) failWith(fn func(op, service, user string) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failFn = fn
+}
+
+// count returns how many times op was called for user.
+func (m *memoryBackend) count(op, user string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls[op+":"+user]
+}
+
+func (m *memoryBackend) record(op, service, user string) error {
+	m.calls[op+":"+user]++
+	if m.failFn != nil {
+		return m.failFn(op, service, user)
+	}
+	return nil
+}
+
+func (m *memoryBackend) Get(service, user string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.record("Get", service, user); err != nil {
+		return "", err
+	}
+	if v, ok := m.store[service][user]; ok {
+		return v, nil
+	}
+	return "", zkeyring.ErrNotFound
+}
+
+func (m *memoryBackend) Set(service, user, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.record("Set", service, user); err != nil {
+		return err
+	}
+	if m.store[service] == nil {
+		m.store[service] = make(map[string]string)
+	}
+	m.store[service][user] = value
+	return nil
+}
+
+func (m *memoryBackend) Delete(service, user string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.record("Delete", service, user); err != nil {
+		return err
+	}
+	if _, ok := m.store[service][user]; !ok {
+		return zkeyring.ErrNotFound
+	}
+	delete(m.store[service], user)
+	return nil
+}
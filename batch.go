@@ -0,0 +1,323 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/agentplexus/omnivault/vault"
+	zkeyring "github.com/zalando/go-keyring"
+)
+
+// BatchResult is the outcome of a single path in a batch operation.
+type BatchResult struct {
+	// Path is the canonical secret path this result refers to, or the path
+	// as given if it was rejected by Config.PathRules or shares its
+	// canonical path with another path of a SetMany.
+	Path string
+
+	// Secret is the retrieved secret. It is only set by GetMany on success.
+	Secret *vault.Secret
+
+	// Err is the error for this path, or nil on success.
+	Err error
+}
+
+// GetMany retrieves multiple secrets, running up to Config.BatchConcurrency
+// backend reads in parallel.
+//
+// Results are returned in the same order as paths. The returned error joins
+// all per-path errors and is nil only if every path was read successfully.
+func (p *Provider) GetMany(ctx context.Context, paths []string) ([]BatchResult, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return nil, vault.NewVaultError("GetMany", "", p.Name(), vault.ErrClosed)
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	results := make([]BatchResult, len(paths))
+	p.parallel(len(paths), func(i int) {
+		path, err := p.canonical("GetMany", paths[i])
+		if err != nil {
+			results[i] = BatchResult{Path: paths[i], Err: err}
+			return
+		}
+		results[i].Path = path
+		if err := ctx.Err(); err != nil {
+			results[i].Err = vault.NewVaultError("GetMany", path, p.Name(), err)
+			return
+		}
+		resp, err := p.handle(ctx, &Request{Op: "GetMany", Path: path}, func(ctx context.Context, req *Request) (*Response, error) {
+			secret, err := p.get(ctx, "GetMany", path)
+			return &Response{Secret: secret}, err
+		})
+		results[i].Secret, results[i].Err = resp.Secret, err
+	})
+
+	return results, joinBatchErrors(results)
+}
+
+// SetMany stores multiple secrets, running up to Config.BatchConcurrency
+// backend writes in parallel. The locks of all paths are taken once for the
+// whole batch and the index is updated with a single save.
+//
+// Results are returned sorted by path. The returned error joins all per-path
+// errors; paths that failed are left unchanged and are not added to the index.
+// Paths with the same canonical form, such as "a/b" and "a//b", are all
+// rejected with vault.ErrInvalidPath, since only one value could be stored.
+func (p *Provider) SetMany(ctx context.Context, secrets map[string]*vault.Secret) ([]BatchResult, error) {
+	if err := p.ensureRecovered(ctx); err != nil {
+		return nil, vault.NewVaultError("SetMany", "", p.Name(), err)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return nil, vault.NewVaultError("SetMany", "", p.Name(), vault.ErrClosed)
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	paths := make([]string, 0, len(secrets))
+	for path := range secrets {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	results, valid := p.canonicalBatch("SetMany", paths)
+	for i, first := range batchDuplicates(results) {
+		if first >= 0 {
+			results[i] = BatchResult{Path: paths[i], Err: p.collision("SetMany", paths[i], paths[first])}
+			results[first] = BatchResult{Path: paths[first], Err: p.collision("SetMany", paths[first], paths[i])}
+		}
+	}
+	unlock := p.locks.lock(valid...)
+	defer unlock()
+
+	p.parallel(len(paths), func(i int) {
+		if results[i].Err != nil {
+			return
+		}
+		path := results[i].Path
+		if err := ctx.Err(); err != nil {
+			results[i].Err = vault.NewVaultError("SetMany", path, p.Name(), err)
+			return
+		}
+		secret := secrets[paths[i]]
+		_, results[i].Err = p.handle(ctx, &Request{Op: "SetMany", Path: path, Secret: secret}, func(ctx context.Context, req *Request) (*Response, error) {
+			value, err := p.encode(secret)
+			if err != nil {
+				return nil, vault.NewVaultError("SetMany", path, p.Name(), err)
+			}
+			if err := p.write(ctx, path, value); err != nil {
+				return nil, vault.NewVaultError("SetMany", path, p.Name(), err)
+			}
+			return nil, nil
+		})
+	})
+
+	var added []string
+	for _, r := range results {
+		if r.Err == nil && !isReservedKey(r.Path) {
+			added = append(added, r.Path)
+		}
+	}
+	if len(added) > 0 {
+		ctx, cancel := p.detach(ctx)
+		defer cancel()
+		p.updateIndex(ctx, added, nil)
+	}
+
+	return results, joinBatchErrors(results)
+}
+
+// DeleteMany removes multiple secrets, running up to Config.BatchConcurrency
+// backend deletes in parallel. The locks of all paths are taken once for
+// the whole batch and the index is updated with a single save.
+//
+// Results are returned in the same order as paths. Deleting a secret that
+// doesn't exist is not an error. The returned error joins all per-path errors.
+// Paths with the same canonical form are deleted once and share a result.
+func (p *Provider) DeleteMany(ctx context.Context, paths []string) ([]BatchResult, error) {
+	if err := p.ensureRecovered(ctx); err != nil {
+		return nil, vault.NewVaultError("DeleteMany", "", p.Name(), err)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return nil, vault.NewVaultError("DeleteMany", "", p.Name(), vault.ErrClosed)
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	results, valid := p.canonicalBatch("DeleteMany", paths)
+	duplicates := batchDuplicates(results)
+	unlock := p.locks.lock(valid...)
+	defer unlock()
+
+	p.parallel(len(paths), func(i int) {
+		if results[i].Err != nil || duplicates[i] >= 0 {
+			return
+		}
+		path := results[i].Path
+		if err := ctx.Err(); err != nil {
+			results[i].Err = vault.NewVaultError("DeleteMany", path, p.Name(), err)
+			return
+		}
+		_, results[i].Err = p.handle(ctx, &Request{Op: "DeleteMany", Path: path}, func(ctx context.Context, req *Request) (*Response, error) {
+			err := p.remove(ctx, path)
+			if err != nil && !errors.Is(err, zkeyring.ErrNotFound) {
+				return nil, vault.NewVaultError("DeleteMany", path, p.Name(), err)
+			}
+			return nil, nil
+		})
+	})
+
+	var removed []string
+	for i, first := range duplicates {
+		if first >= 0 {
+			results[i].Err = results[first].Err
+			continue
+		}
+		if results[i].Err == nil && !isReservedKey(results[i].Path) {
+			removed = append(removed, results[i].Path)
+		}
+	}
+	if len(removed) > 0 {
+		ctx, cancel := p.detach(ctx)
+		defer cancel()
+		p.updateIndex(ctx, nil, removed)
+	}
+
+	return results, joinBatchErrors(results)
+}
+
+// GetBatch implements vault.BatchVault.
+// Secrets that don't exist are omitted from the returned map.
+func (p *Provider) GetBatch(ctx context.Context, paths []string) (map[string]*vault.Secret, error) {
+	results, err := p.GetMany(ctx, paths)
+	if results == nil {
+		return nil, err
+	}
+
+	secrets := make(map[string]*vault.Secret, len(results))
+	var errs []error
+	for _, r := range results {
+		switch {
+		case r.Err == nil:
+			secrets[r.Path] = r.Secret
+		case !errors.Is(r.Err, vault.ErrSecretNotFound):
+			errs = append(errs, r.Err)
+		}
+	}
+	return secrets, errors.Join(errs...)
+}
+
+// SetBatch implements vault.BatchVault.
+func (p *Provider) SetBatch(ctx context.Context, secrets map[string]*vault.Secret) error {
+	_, err := p.SetMany(ctx, secrets)
+	return err
+}
+
+// DeleteBatch implements vault.BatchVault.
+func (p *Provider) DeleteBatch(ctx context.Context, paths []string) error {
+	_, err := p.DeleteMany(ctx, paths)
+	return err
+}
+
+// canonicalBatch returns a result for each of paths holding its canonical
+// form, or the error rejecting it, along with the accepted canonical paths.
+func (p *Provider) canonicalBatch(op string, paths []string) ([]BatchResult, []string) {
+	results := make([]BatchResult, len(paths))
+	valid := make([]string, 0, len(paths))
+	for i, path := range paths {
+		canonical, err := p.canonical(op, path)
+		if err != nil {
+			results[i] = BatchResult{Path: path, Err: err}
+			continue
+		}
+		results[i].Path = canonical
+		valid = append(valid, canonical)
+	}
+	return results, valid
+}
+
+// batchDuplicates returns, for each result, the index of the first earlier
+// accepted result with the same canonical path, or -1 if there is none.
+func batchDuplicates(results []BatchResult) []int {
+	duplicates := make([]int, len(results))
+	first := make(map[string]int, len(results))
+	for i, r := range results {
+		duplicates[i] = -1
+		if r.Err != nil {
+			continue
+		}
+		if j, ok := first[r.Path]; ok {
+			duplicates[i] = j
+			continue
+		}
+		first[r.Path] = i
+	}
+	return duplicates
+}
+
+// collision returns the error rejecting path in a batch because other has
+// the same canonical form.
+func (p *Provider) collision(op, path, other string) error {
+	return vault.NewVaultError(op, path, p.Name(),
+		&InvalidPathError{Path: path, Reason: fmt.Sprintf("same canonical path as %q", other)})
+}
+
+// parallel calls fn for every index in [0, n) using at most
+// Config.BatchConcurrency goroutines, and waits for all calls to finish.
+func (p *Provider) parallel(n int, fn func(i int)) {
+	workers := min(p.config.BatchConcurrency, n)
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// joinBatchErrors joins the per-path errors of a batch.
+func joinBatchErrors(results []BatchResult) error {
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Ensure Provider implements vault.BatchVault.
+var _ vault.BatchVault = (*Provider)(nil)
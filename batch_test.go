@@ -0,0 +1,197 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+func TestProvider_SetMany(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBackend()
+	useBackend(t, b)
+
+	p := New(Config{ServiceName: "test-set-many", BatchConcurrency: 8})
+	defer p.Close()
+
+	secrets := make(map[string]*vault.Secret)
+	for i := 0; i < 50; i++ {
+		secrets[fmt.Sprintf("batch/key%02d", i)] = &vault.Secret{Value: fmt.Sprintf("value%d", i)}
+	}
+
+	results, err := p.SetMany(ctx, secrets)
+	if err != nil {
+		t.Fatalf("SetMany failed: %v", err)
+	}
+	if len(results) != 50 {
+		t.Fatalf("expected 50 results, got %d", len(results))
+	}
+	if results[0].Path != "batch/key00" || results[49].Path != "batch/key49" {
+		t.Errorf("expected results sorted by path, got %q..%q", results[0].Path, results[49].Path)
+	}
+
+	if n := b.count("Set", indexKey); n != 1 {
+		t.Errorf("expected a single index write, got %d", n)
+	}
+
+	list, err := p.List(ctx, "batch/")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 50 {
+		t.Errorf("expected 50 indexed paths, got %d", len(list))
+	}
+}
+
+func TestProvider_SetMany_PartialFailure(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBackend()
+	useBackend(t, b)
+
+	p := New(Config{ServiceName: "test-set-many-fail"})
+	defer p.Close()
+
+	errBoom := errors.New("boom")
+	b.failWith(func(op, _, user string) error {
+		if op == "Set" && user == "bad" {
+			return errBoom
+		}
+		return nil
+	})
+
+	results, err := p.SetMany(ctx, map[string]*vault.Secret{
+		"good": {Value: "1"},
+		"bad":  {Value: "2"},
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected joined error to wrap errBoom, got %v", err)
+	}
+	for _, r := range results {
+		switch r.Path {
+		case "good":
+			if r.Err != nil {
+				t.Errorf("expected good to succeed, got %v", r.Err)
+			}
+		case "bad":
+			if !errors.Is(r.Err, errBoom) {
+				t.Errorf("expected bad to fail with errBoom, got %v", r.Err)
+			}
+		}
+	}
+
+	list, _ := p.List(ctx, "")
+	if len(list) != 1 || list[0] != "good" {
+		t.Errorf("expected only successful path in index, got %v", list)
+	}
+}
+
+func TestProvider_GetMany(t *testing.T) {
+	ctx := context.Background()
+	p := New(Config{ServiceName: "test-get-many"})
+	defer p.Close()
+
+	_, err := p.SetMany(ctx, map[string]*vault.Secret{
+		"a": {Value: "1"},
+		"b": {Value: "2"},
+	})
+	if err != nil {
+		t.Fatalf("SetMany failed: %v", err)
+	}
+	defer func() { _, _ = p.DeleteMany(ctx, []string{"a", "b"}) }()
+
+	results, err := p.GetMany(ctx, []string{"b", "missing", "a"})
+	if !errors.Is(err, vault.ErrSecretNotFound) {
+		t.Errorf("expected joined ErrSecretNotFound, got %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if results[0].Path != "b" || results[0].Secret == nil || results[0].Secret.Value != "2" {
+		t.Errorf("unexpected result for b: %+v", results[0])
+	}
+	if !errors.Is(results[1].Err, vault.ErrSecretNotFound) {
+		t.Errorf("expected ErrSecretNotFound for missing, got %v", results[1].Err)
+	}
+	if results[2].Secret == nil || results[2].Secret.Value != "1" {
+		t.Errorf("unexpected result for a: %+v", results[2])
+	}
+}
+
+func TestProvider_DeleteMany(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBackend()
+	useBackend(t, b)
+
+	p := New(Config{ServiceName: "test-delete-many"})
+	defer p.Close()
+
+	_, err := p.SetMany(ctx, map[string]*vault.Secret{
+		"x/1": {Value: "1"},
+		"x/2": {Value: "2"},
+		"y":   {Value: "3"},
+	})
+	if err != nil {
+		t.Fatalf("SetMany failed: %v", err)
+	}
+
+	results, err := p.DeleteMany(ctx, []string{"x/1", "x/2", "never-existed"})
+	if err != nil {
+		t.Fatalf("DeleteMany failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Errorf("expected 3 results, got %d", len(results))
+	}
+	if n := b.count("Set", indexKey); n != 2 {
+		t.Errorf("expected one index write per batch (2 total), got %d", n)
+	}
+
+	list, _ := p.List(ctx, "")
+	if len(list) != 1 || list[0] != "y" {
+		t.Errorf("expected only y to remain, got %v", list)
+	}
+}
+
+func TestProvider_BatchVault(t *testing.T) {
+	ctx := context.Background()
+	p := New(Config{ServiceName: "test-batch-vault"})
+	defer p.Close()
+
+	var bv vault.BatchVault = p
+	if err := bv.SetBatch(ctx, map[string]*vault.Secret{"k1": {Value: "v1"}}); err != nil {
+		t.Fatalf("SetBatch failed: %v", err)
+	}
+
+	secrets, err := bv.GetBatch(ctx, []string{"k1", "missing"})
+	if err != nil {
+		t.Fatalf("GetBatch should ignore missing secrets, got %v", err)
+	}
+	if len(secrets) != 1 || secrets["k1"].Value != "v1" {
+		t.Errorf("unexpected GetBatch result %v", secrets)
+	}
+
+	if err := bv.DeleteBatch(ctx, []string{"k1"}); err != nil {
+		t.Fatalf("DeleteBatch failed: %v", err)
+	}
+	if exists, _ := p.Exists(ctx, "k1"); exists {
+		t.Error("expected k1 to be deleted")
+	}
+}
+
+func TestProvider_Batch_Closed(t *testing.T) {
+	ctx := context.Background()
+	p := New(Config{ServiceName: "test-batch-closed"})
+	_ = p.Close()
+
+	if _, err := p.GetMany(ctx, []string{"a"}); !errors.Is(err, vault.ErrClosed) {
+		t.Errorf("expected ErrClosed from GetMany, got %v", err)
+	}
+	if _, err := p.SetMany(ctx, map[string]*vault.Secret{"a": {}}); !errors.Is(err, vault.ErrClosed) {
+		t.Errorf("expected ErrClosed from SetMany, got %v", err)
+	}
+	if _, err := p.DeleteMany(ctx, []string{"a"}); !errors.Is(err, vault.ErrClosed) {
+		t.Errorf("expected ErrClosed from DeleteMany, got %v", err)
+	}
+}
@@ -0,0 +1,289 @@
+package keyring
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	zkeyring "github.com/zalando/go-keyring"
+)
+
+// DefaultCacheEntries is the default maximum number of cached secrets.
+const DefaultCacheEntries = 1024
+
+// CacheConfig controls the optional in-process read cache, which serves
+// Get, GetMany, GetSecure and Exists without a backend round trip.
+//
+// The cache only sees changes made through the same Provider: Set, Delete
+// and every other write invalidate the affected paths, but changes made by
+// other processes go unnoticed until the cached entry expires.
+type CacheConfig struct {
+	// TTL is how long a secret read from the keyring is served from the
+	// cache. Zero disables the cache unless TTLFunc is set.
+	TTL time.Duration
+
+	// TTLFunc, if set, returns the TTL for each path, overriding TTL.
+	// Paths for which it returns zero or less are not cached.
+	TTLFunc func(path string) time.Duration
+
+	// NegativeTTL is how long a "not found" result is cached.
+	// Zero disables negative caching.
+	NegativeTTL time.Duration
+
+	// MaxEntries bounds the number of cached paths; the least recently
+	// used entry is evicted to make room.
+	// Default: 1024
+	MaxEntries int
+
+	// Secure holds cached values only in locked, zeroizable buffers, like
+	// GetSecure, and zeroes them on eviction, invalidation and Close.
+	// Values returned by Get are still ordinary strings.
+	Secure bool
+}
+
+// enabled reports whether the configuration turns the cache on.
+func (c CacheConfig) enabled() bool {
+	return c.TTL > 0 || c.TTLFunc != nil
+}
+
+// CacheStats reports the activity of the read cache.
+type CacheStats struct {
+	// Hits counts reads served from a cached value.
+	Hits uint64
+
+	// NegativeHits counts reads served from a cached "not found" result.
+	NegativeHits uint64
+
+	// Misses counts reads that went to the keyring.
+	Misses uint64
+
+	// Evictions counts entries dropped to stay within MaxEntries.
+	Evictions uint64
+
+	// Entries is the number of paths currently cached.
+	Entries int
+}
+
+// CacheStats returns the read cache statistics. They are all zero if the
+// cache is disabled.
+func (p *Provider) CacheStats() CacheStats {
+	if p.cache == nil {
+		return CacheStats{}
+	}
+	return p.cache.snapshot()
+}
+
+// cachedRead returns the value stored at path, from the cache if possible,
+// and otherwise from a shared backend read that caches what it finds.
+func (p *Provider) cachedRead(ctx context.Context, path string) (string, error) {
+	if p.cache != nil {
+		if value, found, hit := p.cache.get(path); hit {
+			if !found {
+				return "", zkeyring.ErrNotFound
+			}
+			return value, nil
+		}
+	}
+	return p.sharedRead(ctx, path)
+}
+
+// invalidate drops path from the cache, and makes the next read of path
+// start a new backend call rather than join one already in flight.
+func (p *Provider) invalidate(path string) {
+	if p.cache != nil {
+		p.cache.remove(path)
+	}
+	p.reads.Forget(path)
+	p.reads.Forget(existsKey(path))
+}
+
+// secretCache is a TTL and LRU bounded cache of stored values by path.
+type secretCache struct {
+	config CacheConfig
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // of *cacheEntry
+	lru     *list.List               // most recently used first
+	gen     uint64                   // incremented by every invalidation
+	stats   CacheStats
+}
+
+// cacheEntry is a cached read of one path.
+type cacheEntry struct {
+	path    string
+	value   string        // the stored value, unless config.Secure
+	buf     *lockedBuffer // the stored value, if config.Secure
+	found   bool
+	expires time.Time
+}
+
+// newSecretCache returns a cache for config, or nil if it is disabled.
+func newSecretCache(config CacheConfig) *secretCache {
+	if !config.enabled() {
+		return nil
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = DefaultCacheEntries
+	}
+	return &secretCache{
+		config:  config,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// get returns the cached value of path. hit is false if path isn't cached
+// or has expired; found is false for a cached "not found".
+func (c *secretCache) get(path string) (value string, found, hit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := c.lookup(path)
+	switch {
+	case entry == nil:
+		return "", false, false
+	case !entry.found:
+		return "", false, true
+	case entry.buf != nil:
+		return string(entry.buf.bytes()), true, true
+	default:
+		return entry.value, true, true
+	}
+}
+
+// getLocked is like get, but returns the cached value in a new locked
+// buffer, which the caller must destroy, without copying it to the heap
+// when the cache is Secure.
+func (c *secretCache) getLocked(path string) (buf *lockedBuffer, found, hit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := c.lookup(path)
+	switch {
+	case entry == nil:
+		return nil, false, false
+	case !entry.found:
+		return nil, false, true
+	}
+	if entry.buf != nil {
+		buf = newLockedBuffer(len(entry.buf.data))
+		copy(buf.data, entry.buf.data)
+	} else {
+		buf = newLockedBuffer(len(entry.value))
+		copy(buf.data, entry.value)
+	}
+	return buf, true, true
+}
+
+// lookup returns the unexpired entry of path, or nil, and records the
+// lookup in the statistics. The caller must hold c.mu.
+func (c *secretCache) lookup(path string) *cacheEntry {
+	elem, ok := c.entries[path]
+	if !ok {
+		c.stats.Misses++
+		return nil
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.drop(elem)
+		c.stats.Misses++
+		return nil
+	}
+
+	c.lru.MoveToFront(elem)
+	if entry.found {
+		c.stats.Hits++
+	} else {
+		c.stats.NegativeHits++
+	}
+	return entry
+}
+
+// generation returns a token for put that changes whenever an entry is
+// invalidated.
+func (c *secretCache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+// put caches the result of reading path, unless the cache was invalidated
+// since gen was obtained, in which case the result may already be stale.
+func (c *secretCache) put(gen uint64, path, value string, found bool) {
+	ttl := c.config.NegativeTTL
+	if found {
+		ttl = c.config.TTL
+		if c.config.TTLFunc != nil {
+			ttl = c.config.TTLFunc(path)
+		}
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	entry := &cacheEntry{path: path, found: found, expires: c.now().Add(ttl)}
+	if found && c.config.Secure {
+		entry.buf = newLockedBuffer(len(value))
+		copy(entry.buf.data, value)
+	} else {
+		entry.value = value
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		if entry.buf != nil {
+			entry.buf.destroy()
+		}
+		return
+	}
+	if elem, ok := c.entries[path]; ok {
+		c.drop(elem)
+	}
+	c.entries[path] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.config.MaxEntries {
+		c.drop(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+// remove drops path from the cache.
+func (c *secretCache) remove(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	if elem, ok := c.entries[path]; ok {
+		c.drop(elem)
+	}
+}
+
+// purge drops every entry.
+func (c *secretCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for c.lru.Len() > 0 {
+		c.drop(c.lru.Back())
+	}
+}
+
+// snapshot returns the current statistics.
+func (c *secretCache) snapshot() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	return stats
+}
+
+// drop removes elem and zeroes its value. The caller must hold c.mu.
+func (c *secretCache) drop(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, entry.path)
+	if entry.buf != nil {
+		entry.buf.destroy()
+	}
+}
@@ -0,0 +1,238 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+// fakeClock is a manually advanced clock for cache expiry tests.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newCachedProvider(t *testing.T, b *memoryBackend, cache CacheConfig) (*Provider, *fakeClock) {
+	t.Helper()
+	useBackend(t, b)
+	p := New(Config{ServiceName: "cache-test", Cache: cache})
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	p.cache.now = clock.now
+	return p, clock
+}
+
+func TestCacheReadThrough(t *testing.T) {
+	b := newMemoryBackend()
+	p, clock := newCachedProvider(t, b, CacheConfig{TTL: time.Minute})
+	ctx := context.Background()
+
+	if err := p.Set(ctx, "api-key", &vault.Secret{Value: "v1"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if secret, err := p.Get(ctx, "api-key"); err != nil || secret.Value != "v1" {
+			t.Fatalf("Get() = %v, %v", secret, err)
+		}
+	}
+	if ok, err := p.Exists(ctx, "api-key"); err != nil || !ok {
+		t.Errorf("Exists() = %v, %v", ok, err)
+	}
+	if n := b.count("Get", "api-key"); n != 1 {
+		t.Errorf("backend Get called %d times, want 1", n)
+	}
+	stats := p.CacheStats()
+	if stats.Hits != 3 || stats.Misses != 1 || stats.Entries != 1 {
+		t.Errorf("CacheStats() = %+v, want 3 hits, 1 miss, 1 entry", stats)
+	}
+
+	// Our own writes invalidate the entry.
+	if err := p.Set(ctx, "api-key", &vault.Secret{Value: "v2"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if secret, _ := p.Get(ctx, "api-key"); secret == nil || secret.Value != "v2" {
+		t.Errorf("Get() after Set = %v, want v2", secret)
+	}
+
+	// Changes by others are seen once the entry expires.
+	b.Set("cache-test", "api-key", "v3")
+	if secret, _ := p.Get(ctx, "api-key"); secret.Value != "v2" {
+		t.Errorf("Get() before expiry = %q, want cached v2", secret.Value)
+	}
+	clock.advance(time.Minute)
+	if secret, _ := p.Get(ctx, "api-key"); secret.Value != "v3" {
+		t.Errorf("Get() after expiry = %q, want v3", secret.Value)
+	}
+
+	if err := p.Delete(ctx, "api-key"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := p.Get(ctx, "api-key"); !errors.Is(err, vault.ErrSecretNotFound) {
+		t.Errorf("Get() after Delete error = %v, want ErrSecretNotFound", err)
+	}
+}
+
+func TestCacheNegative(t *testing.T) {
+	b := newMemoryBackend()
+	p, clock := newCachedProvider(t, b, CacheConfig{TTL: time.Minute, NegativeTTL: time.Second})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if ok, err := p.Exists(ctx, "missing"); err != nil || ok {
+			t.Fatalf("Exists() = %v, %v", ok, err)
+		}
+	}
+	if n := b.count("Get", "missing"); n != 1 {
+		t.Errorf("backend Get called %d times, want 1", n)
+	}
+	if stats := p.CacheStats(); stats.NegativeHits != 1 {
+		t.Errorf("CacheStats().NegativeHits = %d, want 1", stats.NegativeHits)
+	}
+
+	b.Set("cache-test", "missing", "now here")
+	clock.advance(time.Second)
+	if ok, _ := p.Exists(ctx, "missing"); !ok {
+		t.Error("Exists() after negative TTL = false, want true")
+	}
+}
+
+func TestCacheLRU(t *testing.T) {
+	b := newMemoryBackend()
+	p, _ := newCachedProvider(t, b, CacheConfig{TTL: time.Minute, MaxEntries: 2})
+	ctx := context.Background()
+
+	for _, path := range []string{"a", "b", "c"} {
+		if err := p.Set(ctx, path, &vault.Secret{Value: path}); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+	p.Get(ctx, "a")
+	p.Get(ctx, "b")
+	p.Get(ctx, "a") // b is now least recently used
+	p.Get(ctx, "c") // evicts b
+
+	if stats := p.CacheStats(); stats.Evictions != 1 || stats.Entries != 2 {
+		t.Errorf("CacheStats() = %+v, want 1 eviction, 2 entries", stats)
+	}
+	p.Get(ctx, "a")
+	p.Get(ctx, "b")
+	if n := b.count("Get", "a"); n != 1 {
+		t.Errorf("backend Get(a) called %d times, want 1", n)
+	}
+	if n := b.count("Get", "b"); n != 2 {
+		t.Errorf("backend Get(b) called %d times, want 2 after eviction", n)
+	}
+}
+
+func TestCacheTTLFunc(t *testing.T) {
+	b := newMemoryBackend()
+	p, _ := newCachedProvider(t, b, CacheConfig{TTLFunc: func(path string) time.Duration {
+		if path == "volatile" {
+			return 0
+		}
+		return time.Minute
+	}})
+	ctx := context.Background()
+
+	p.Set(ctx, "stable", &vault.Secret{Value: "s"})
+	p.Set(ctx, "volatile", &vault.Secret{Value: "v"})
+	for i := 0; i < 2; i++ {
+		p.Get(ctx, "stable")
+		p.Get(ctx, "volatile")
+	}
+	if n := b.count("Get", "stable"); n != 1 {
+		t.Errorf("backend Get(stable) called %d times, want 1", n)
+	}
+	if n := b.count("Get", "volatile"); n != 2 {
+		t.Errorf("backend Get(volatile) called %d times, want 2", n)
+	}
+}
+
+func TestCacheSecure(t *testing.T) {
+	b := newMemoryBackend()
+	p, _ := newCachedProvider(t, b, CacheConfig{TTL: time.Minute, Secure: true})
+	ctx := context.Background()
+
+	p.Set(ctx, "token", &vault.Secret{Value: "s3cret"})
+	p.Get(ctx, "token")
+
+	entry := p.cache.entries["token"].Value.(*cacheEntry)
+	if entry.value != "" || entry.buf == nil || string(entry.buf.bytes()) != "s3cret" {
+		t.Fatalf("secure cache entry = %+v, want value only in a locked buffer", entry)
+	}
+	secure, err := p.GetSecure(ctx, "token")
+	if err != nil || string(secure.Value()) != "s3cret" {
+		t.Errorf("GetSecure() = %v", err)
+	} else {
+		secure.Destroy()
+	}
+
+	buf := entry.buf
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if buf.bytes() != nil || p.CacheStats().Entries != 0 {
+		t.Error("Close() did not purge and zero the cache")
+	}
+}
+
+func TestCacheSecureGetSecureHit(t *testing.T) {
+	b := newMemoryBackend()
+	useBackend(t, b)
+	p := New(Config{ServiceName: "cache-test", JSONFormat: true, Cache: CacheConfig{TTL: time.Minute, Secure: true}})
+	ctx := context.Background()
+
+	p.Set(ctx, "token", &vault.Secret{Value: "s3cret"})
+	p.Set(ctx, "json", &vault.Secret{Value: "v", Fields: map[string]string{"k": "f"}})
+	p.Get(ctx, "token")
+	p.Get(ctx, "json")
+	gets := b.count("Get", "token")
+
+	entry := p.cache.entries["token"].Value.(*cacheEntry)
+	buf, found, hit := p.cache.getLocked("token")
+	if !hit || !found || buf == entry.buf || string(buf.bytes()) != string(entry.buf.bytes()) {
+		t.Fatalf("getLocked() = %v, %v, %v; want a copy of the entry", buf, found, hit)
+	}
+	buf.destroy()
+
+	before := p.CacheStats().Hits
+	secure, err := p.GetSecure(ctx, "token")
+	if err != nil {
+		t.Fatalf("GetSecure() error = %v", err)
+	}
+	defer secure.Destroy()
+	if got := b.count("Get", "token"); got != gets {
+		t.Errorf("GetSecure() made %d backend reads, want a cache hit", got-gets)
+	}
+	if hits := p.CacheStats().Hits; hits != before+1 {
+		t.Errorf("Hits = %d, want %d", hits, before+1)
+	}
+	if want, err := p.Get(ctx, "token"); err != nil || secure.Metadata.Extra[MetadataETag] != want.Metadata.Extra[MetadataETag] {
+		t.Errorf("GetSecure() ETag = %v, want the Get ETag", secure.Metadata.Extra[MetadataETag])
+	}
+
+	fields, err := p.GetSecure(ctx, "json")
+	if err != nil {
+		t.Fatalf("GetSecure() error = %v", err)
+	}
+	defer fields.Destroy()
+
+	// The secrets own their memory: purging the cache must not touch them.
+	p.Close()
+	if string(secure.Value()) != "s3cret" || string(fields.Value()) != "v" || string(fields.Field("k")) != "f" {
+		t.Errorf("GetSecure() values changed after Close: %q, %q, %q", secure.Value(), fields.Value(), fields.Field("k"))
+	}
+}
+
+func TestCacheDisabled(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+	p := New(Config{ServiceName: "cache-test"})
+	if p.cache != nil {
+		t.Error("cache enabled without a TTL")
+	}
+	if stats := p.CacheStats(); stats != (CacheStats{}) {
+		t.Errorf("CacheStats() = %+v, want zero", stats)
+	}
+}
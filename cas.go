@@ -0,0 +1,235 @@
+package keyring
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/agentplexus/omnivault/vault"
+	zkeyring "github.com/zalando/go-keyring"
+)
+
+// MetadataETag is the vault.Metadata.Extra key holding a secret's ETag.
+// Secrets returned by Get carry one, unless the key ETags are computed with
+// can't be read or created; pass it to SetIfMatch to make a write
+// conditional on the secret not having changed since it was read.
+const MetadataETag = "etag"
+
+const (
+	// etagKeyName is the key of the secret ETags are computed with.
+	etagKeyName = "__omnivault_etag_key__"
+
+	// etagKeySize is the size of the ETag key in bytes.
+	etagKeySize = 32
+
+	// etagRetryDelay is how long reads go without ETags after the ETag key
+	// couldn't be loaded, before they try again.
+	etagRetryDelay = time.Minute
+)
+
+// errETagKeyBackoff is returned for reads while the ETag key is not retried.
+var errETagKeyBackoff = errors.New("etag key unavailable")
+
+// ETag returns the ETag recorded in a secret's metadata, or "" if it has none.
+func ETag(secret *vault.Secret) string {
+	if secret == nil {
+		return ""
+	}
+	etag, _ := secret.Metadata.Extra[MetadataETag].(string)
+	return etag
+}
+
+// SetIfNotExists stores a secret only if nothing is stored at path yet.
+// It returns a *PreconditionError matching ErrPreconditionFailed and
+// vault.ErrAlreadyExists if the path is already in use.
+//
+// Like SetIfMatch, it is only atomic against writes through the same
+// Provider.
+func (p *Provider) SetIfNotExists(ctx context.Context, path string, secret *vault.Secret) error {
+	return p.setIf(ctx, "SetIfNotExists", path, secret, "")
+}
+
+// SetIfMatch stores a secret only if the stored secret's ETag equals etag,
+// i.e. it hasn't been changed since the caller read it with Get. It returns
+// a *PreconditionError matching ErrPreconditionFailed if the secret was
+// modified, or deleted, in the meantime. An empty etag matches only a
+// secret that doesn't exist.
+//
+// The keyring offers no compare-and-swap, so the stored value is read,
+// compared and then written. The path lock makes that atomic against other
+// writes through this Provider only: another process, or another Provider,
+// can change the secret between the check and the write.
+func (p *Provider) SetIfMatch(ctx context.Context, path string, secret *vault.Secret, etag string) error {
+	return p.setIf(ctx, "SetIfMatch", path, secret, etag)
+}
+
+// setIf performs a conditional write under the lock of path.
+func (p *Provider) setIf(ctx context.Context, op, path string, secret *vault.Secret, etag string) error {
+	if err := p.ensureRecovered(ctx); err != nil {
+		return vault.NewVaultError(op, path, p.Name(), err)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return vault.NewVaultError(op, path, p.Name(), vault.ErrClosed)
+	}
+	path, err := p.canonical(op, path)
+	if err != nil {
+		return err
+	}
+	unlock := p.locks.lock(path)
+	defer unlock()
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	_, err = p.handle(ctx, &Request{Op: op, Path: path, Secret: secret, ETag: etag}, func(ctx context.Context, req *Request) (*Response, error) {
+		return nil, p.putIf(ctx, op, path, secret, etag)
+	})
+	return err
+}
+
+// putIf stores secret if the ETag of the stored value equals etag. The
+// caller must hold p.mu for reading along with the lock of path.
+func (p *Provider) putIf(ctx context.Context, op, path string, secret *vault.Secret, etag string) error {
+	actual := ""
+	value, err := p.read(ctx, path)
+	if err != nil && !errors.Is(err, zkeyring.ErrNotFound) {
+		return vault.NewVaultError(op, path, p.Name(), err)
+	}
+
+	if err == nil {
+		if actual, err = p.etag(ctx, value, false); err != nil {
+			return vault.NewVaultError(op, path, p.Name(), err)
+		}
+	}
+
+	if actual != etag {
+		// The key may have been replaced by another process creating it
+		// at the same time; pick up the stored one for the next attempt.
+		p.forgetETagKey()
+		return vault.NewVaultError(op, path, p.Name(), &PreconditionError{
+			Path:     path,
+			Expected: etag,
+			Actual:   actual,
+		})
+	}
+
+	return p.put(ctx, op, path, secret)
+}
+
+// readETag returns the ETag of value, read from path, or "" if the ETag
+// key is unavailable, for example because the keyring denies the write
+// that creates it: a read doesn't fail for lack of an ETag.
+func (p *Provider) readETag(ctx context.Context, path, value string) string {
+	etag, err := p.etag(ctx, value, true)
+	if err != nil {
+		if !errors.Is(err, errETagKeyBackoff) {
+			p.log.WarnContext(ctx, "keyring: etag key unavailable, returning secrets without ETags",
+				p.logPath(path), slog.Any("error", err), slog.Duration("retry", etagRetryDelay))
+		}
+		return ""
+	}
+	return etag
+}
+
+// etag returns the ETag of a stored value: a truncated HMAC-SHA256 of its
+// exact stored bytes, so any change made through any client alters it.
+// The HMAC key is kept in the keyring, so ETags that end up in logs can't
+// be used to guess short secrets such as PINs. For reads, the key is not
+// retried for etagRetryDelay after loading it failed.
+func (p *Provider) etag(ctx context.Context, value string, read bool) (string, error) {
+	key, err := p.loadETagKey(ctx, read)
+	if err != nil {
+		return "", fmt.Errorf("etag key: %w", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(stringBytes(value)) // don't leave a heap copy of the value
+	return hex.EncodeToString(mac.Sum(nil)[:16]), nil
+}
+
+// loadETagKey returns the ETag key, reading it from the keyring on first
+// use and creating it there if no client has done so yet. If read is set
+// and loading the key failed less than etagRetryDelay ago, it returns
+// errETagKeyBackoff rather than trying again.
+func (p *Provider) loadETagKey(ctx context.Context, read bool) ([]byte, error) {
+	p.etagMu.Lock()
+	defer p.etagMu.Unlock()
+	if p.etagKey != nil {
+		return p.etagKey, nil
+	}
+	if read && time.Now().Before(p.etagRetry) {
+		return nil, errETagKeyBackoff
+	}
+
+	key, err := p.fetchETagKey(ctx)
+	if err != nil {
+		p.etagRetry = time.Now().Add(etagRetryDelay)
+		return nil, err
+	}
+	p.etagKey, p.etagRetry = key, time.Time{}
+	return key, nil
+}
+
+// fetchETagKey reads the ETag key from the keyring, creating it if needed.
+func (p *Provider) fetchETagKey(ctx context.Context) ([]byte, error) {
+	value, err := p.read(ctx, etagKeyName)
+	if errors.Is(err, zkeyring.ErrNotFound) {
+		key := make([]byte, etagKeySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := p.write(ctx, etagKeyName, p.sealRecord(etagKeyName, []byte(hex.EncodeToString(key)))); err != nil {
+			return nil, err
+		}
+		// Read the key back, in case another process stored its own.
+		value, err = p.read(ctx, etagKeyName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := p.openRecord(etagKeyName, value)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(string(data))
+	if err != nil || len(key) != etagKeySize {
+		return nil, errors.New("malformed etag key")
+	}
+	return key, nil
+}
+
+// forgetETagKey makes the next ETag computation read the key again.
+func (p *Provider) forgetETagKey() {
+	p.etagMu.Lock()
+	defer p.etagMu.Unlock()
+	p.etagKey = nil
+}
+
+// withoutETag returns secret without the ETag in its metadata, so that a
+// secret read with Get and written back doesn't persist a stale ETag.
+func withoutETag(secret *vault.Secret) *vault.Secret {
+	if _, ok := secret.Metadata.Extra[MetadataETag]; !ok {
+		return secret
+	}
+	clone := *secret
+	clone.Metadata.Extra = make(map[string]any, len(secret.Metadata.Extra)-1)
+	for k, v := range secret.Metadata.Extra {
+		if k != MetadataETag {
+			clone.Metadata.Extra[k] = v
+		}
+	}
+	if len(clone.Metadata.Extra) == 0 {
+		clone.Metadata.Extra = nil
+	}
+	return &clone
+}
@@ -0,0 +1,196 @@
+package keyring
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+func TestProvider_SetIfNotExists(t *testing.T) {
+	ctx := context.Background()
+	p := New(Config{ServiceName: "test-set-if-not-exists"})
+	defer p.Close()
+	defer func() { _ = p.Delete(ctx, "key") }()
+
+	if err := p.SetIfNotExists(ctx, "key", &vault.Secret{Value: "first"}); err != nil {
+		t.Fatalf("SetIfNotExists failed: %v", err)
+	}
+
+	err := p.SetIfNotExists(ctx, "key", &vault.Secret{Value: "second"})
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("expected ErrPreconditionFailed, got %v", err)
+	}
+	if !errors.Is(err, vault.ErrAlreadyExists) {
+		t.Errorf("expected ErrAlreadyExists, got %v", err)
+	}
+	var pe *PreconditionError
+	if !errors.As(err, &pe) || pe.Path != "key" || pe.Actual == "" {
+		t.Errorf("expected *PreconditionError with actual etag, got %#v", pe)
+	}
+
+	secret, _ := p.Get(ctx, "key")
+	if secret.Value != "first" {
+		t.Errorf("expected value to be unchanged, got %q", secret.Value)
+	}
+}
+
+func TestProvider_SetIfMatch(t *testing.T) {
+	ctx := context.Background()
+	p := New(Config{ServiceName: "test-set-if-match", JSONFormat: true})
+	defer p.Close()
+	defer func() { _ = p.Delete(ctx, "cred") }()
+
+	if err := p.Set(ctx, "cred", &vault.Secret{Value: "v1"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	// Two instances read the same version
+	a, _ := p.Get(ctx, "cred")
+	b, _ := p.Get(ctx, "cred")
+	if ETag(a) == "" || ETag(a) != ETag(b) {
+		t.Fatalf("expected matching non-empty etags, got %q and %q", ETag(a), ETag(b))
+	}
+
+	// The first rotation wins
+	a.Value = "v2-from-a"
+	if err := p.SetIfMatch(ctx, "cred", a, ETag(a)); err != nil {
+		t.Fatalf("SetIfMatch failed: %v", err)
+	}
+
+	// The second sees a conflict
+	b.Value = "v2-from-b"
+	err := p.SetIfMatch(ctx, "cred", b, ETag(b))
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("expected ErrPreconditionFailed, got %v", err)
+	}
+	if errors.Is(err, vault.ErrAlreadyExists) {
+		t.Error("etag mismatch should not match ErrAlreadyExists")
+	}
+
+	current, _ := p.Get(ctx, "cred")
+	if current.Value != "v2-from-a" {
+		t.Errorf("expected v2-from-a, got %q", current.Value)
+	}
+	if ETag(current) == ETag(a) {
+		t.Error("expected etag to change after write")
+	}
+
+	t.Run("etag is not persisted", func(t *testing.T) {
+		raw, err := p.backend.Get(p.ServiceName(), "cred")
+		if err != nil {
+			t.Fatalf("backend Get failed: %v", err)
+		}
+		var stored vault.Secret
+		if err := json.Unmarshal([]byte(raw), &stored); err != nil {
+			t.Fatalf("unmarshal failed: %v", err)
+		}
+		if _, ok := stored.Metadata.Extra[MetadataETag]; ok {
+			t.Error("expected etag to be stripped before storing")
+		}
+	})
+
+	t.Run("deleted secret", func(t *testing.T) {
+		etag := ETag(current)
+		_ = p.Delete(ctx, "cred")
+		err := p.SetIfMatch(ctx, "cred", current, etag)
+		var pe *PreconditionError
+		if !errors.As(err, &pe) || pe.Actual != "" {
+			t.Errorf("expected precondition error with empty actual etag, got %v", err)
+		}
+	})
+}
+
+func TestProvider_ETagIsKeyed(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBackend()
+	useBackend(t, b)
+
+	p := New(Config{ServiceName: "test-etag-keyed"})
+	defer p.Close()
+	if err := p.Set(ctx, "pin", &vault.Secret{Value: "1234"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	secret, err := p.Get(ctx, "pin")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	etag := ETag(secret)
+	if sum := sha256.Sum256([]byte("1234")); strings.HasPrefix(hex.EncodeToString(sum[:]), etag) {
+		t.Error("ETag is an unkeyed hash of the stored value")
+	}
+	if _, err := b.Get("test-etag-keyed", etagKeyName); err != nil {
+		t.Errorf("ETag key not stored in the keyring: %v", err)
+	}
+
+	// Another provider for the same service uses the same key.
+	other := New(Config{ServiceName: "test-etag-keyed"})
+	defer other.Close()
+	if err := other.SetIfMatch(ctx, "pin", &vault.Secret{Value: "5678"}, etag); err != nil {
+		t.Fatalf("SetIfMatch with an ETag from another provider failed: %v", err)
+	}
+
+	err = p.SetIfMatch(ctx, "pin", &vault.Secret{Value: "0000"}, etag)
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("expected ErrPreconditionFailed, got %v", err)
+	}
+	if strings.Contains(err.Error(), etag) {
+		t.Errorf("error message contains the ETag: %v", err)
+	}
+	if paths, _ := p.List(ctx, ""); len(paths) != 1 {
+		t.Errorf("List() = %v, want only the secret", paths)
+	}
+}
+
+func TestProvider_ETagKeyUnavailable(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBackend()
+	useBackend(t, b)
+	_ = b.Set("test-etag-denied", "token", "s3cret")
+
+	errDenied := errors.New("denied")
+	b.failWith(func(op, _, _ string) error {
+		if op == "Set" {
+			return errDenied
+		}
+		return nil
+	})
+	p := New(Config{ServiceName: "test-etag-denied"})
+	defer p.Close()
+
+	for i := 0; i < 2; i++ {
+		secret, err := p.Get(ctx, "token")
+		if err != nil || secret.Value != "s3cret" {
+			t.Fatalf("Get() on a read-only keyring = %v, %v", secret, err)
+		}
+		if etag := ETag(secret); etag != "" {
+			t.Errorf("Get() ETag = %q, want none", etag)
+		}
+	}
+	if n := b.count("Set", etagKeyName); n != 1 {
+		t.Errorf("ETag key creation tried %d times, want once until the retry delay", n)
+	}
+	secure, err := p.GetSecure(ctx, "token")
+	if err != nil {
+		t.Fatalf("GetSecure() on a read-only keyring error = %v", err)
+	}
+	secure.Destroy()
+
+	// A conditional write still needs the key, and fails without it.
+	if err := p.SetIfMatch(ctx, "token", &vault.Secret{Value: "x"}, "anything"); !errors.Is(err, errDenied) {
+		t.Errorf("SetIfMatch() error = %v, want %v", err, errDenied)
+	}
+
+	b.failWith(nil)
+	if err := p.SetIfMatch(ctx, "token", &vault.Secret{Value: "x"}, "anything"); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("SetIfMatch() error = %v, want ErrPreconditionFailed", err)
+	}
+	if secret, err := p.Get(ctx, "token"); err != nil || ETag(secret) == "" {
+		t.Errorf("Get() once the key exists = %v, %v; want an ETag", secret, err)
+	}
+}
@@ -0,0 +1,56 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+
+	zkeyring "github.com/zalando/go-keyring"
+)
+
+// sharedRead reads path from the backend, sharing a single call between all
+// concurrent readers of the same path, and caches the result.
+func (p *Provider) sharedRead(ctx context.Context, path string) (string, error) {
+	value, err := p.share(ctx, path, func(ctx context.Context) (any, error) {
+		var gen uint64
+		if p.cache != nil {
+			gen = p.cache.generation()
+		}
+		value, err := p.read(ctx, path)
+		if p.cache != nil {
+			switch {
+			case err == nil:
+				p.cache.put(gen, path, value, true)
+			case errors.Is(err, zkeyring.ErrNotFound):
+				p.cache.put(gen, path, "", false)
+			}
+		}
+		return value, err
+	})
+	s, _ := value.(string)
+	return s, err
+}
+
+// share calls fn once for all concurrent callers passing the same key and
+// gives each of them its result.
+//
+// fn runs on a context detached from the caller that started it, so a
+// caller giving up doesn't abort the call for the others. Each caller still
+// returns as soon as its own ctx is done.
+func (p *Provider) share(ctx context.Context, key string, fn func(ctx context.Context) (any, error)) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	results := p.reads.DoChan(key, func() (any, error) {
+		shared, cancel := p.detach(ctx)
+		defer cancel()
+		return fn(shared)
+	})
+
+	select {
+	case r := <-results:
+		return r.Val, r.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,147 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+// blockReads makes backend reads of path block until the returned release
+// function is called. started is closed when the first read begins.
+func blockReads(b *memoryBackend, path string) (started <-chan struct{}, release func()) {
+	begun := make(chan struct{})
+	gate := make(chan struct{})
+	var once sync.Once
+	b.failWith(func(op, service, user string) error {
+		if op == "Get" && user == path {
+			once.Do(func() { close(begun) })
+			<-gate
+		}
+		return nil
+	})
+	return begun, sync.OnceFunc(func() { close(gate) })
+}
+
+func TestConcurrentReadsShareBackendCall(t *testing.T) {
+	b := newMemoryBackend()
+	useBackend(t, b)
+	ctx := context.Background()
+	p := New(Config{ServiceName: "dedupe-test"})
+	if err := p.Set(ctx, "hot", &vault.Secret{Value: "v"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	started, release := blockReads(b, "hot")
+	defer release()
+
+	const readers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, readers)
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				secret, err := p.Get(ctx, "hot")
+				if err == nil && secret.Value != "v" {
+					err = errors.New("wrong value " + secret.Value)
+				}
+				errs <- err
+				return
+			}
+			ok, err := p.Exists(ctx, "hot")
+			if err == nil && !ok {
+				err = errors.New("Exists() = false")
+			}
+			errs <- err
+		}(i)
+	}
+
+	<-started
+	time.Sleep(50 * time.Millisecond) // Let the other readers join the call
+	release()
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if n := b.count("Get", "hot"); n != 1 {
+		t.Errorf("backend Get called %d times for %d concurrent readers, want 1", n, readers)
+	}
+}
+
+func TestSharedReadCancellation(t *testing.T) {
+	b := newMemoryBackend()
+	useBackend(t, b)
+	p := New(Config{ServiceName: "dedupe-test"})
+	if err := p.Set(context.Background(), "hot", &vault.Secret{Value: "v"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	started, release := blockReads(b, "hot")
+	defer release()
+
+	// The first caller starts the shared read, then gives up.
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := p.Get(ctx, "hot")
+		first <- err
+	}()
+	<-started
+
+	second := make(chan error, 1)
+	go func() {
+		secret, err := p.Get(context.Background(), "hot")
+		if err == nil && secret.Value != "v" {
+			err = errors.New("wrong value " + secret.Value)
+		}
+		second <- err
+	}()
+	time.Sleep(50 * time.Millisecond) // Let the second caller join
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled Get() error = %v, want context.Canceled", err)
+	}
+
+	release()
+	if err := <-second; err != nil {
+		t.Errorf("second Get() error = %v; the shared read was aborted", err)
+	}
+	if n := b.count("Get", "hot"); n != 1 {
+		t.Errorf("backend Get called %d times, want 1", n)
+	}
+}
+
+func TestSharedReadForgottenOnWrite(t *testing.T) {
+	b := newMemoryBackend()
+	useBackend(t, b)
+	ctx := context.Background()
+	p := New(Config{ServiceName: "dedupe-test", Cache: CacheConfig{TTL: time.Minute}})
+
+	if err := p.Set(ctx, "key", &vault.Secret{Value: "old"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	// A result read before an invalidation is not cached.
+	gen := p.cache.generation()
+	p.invalidate("key")
+	p.cache.put(gen, "key", "old", true)
+	if _, _, hit := p.cache.get("key"); hit {
+		t.Error("stale read was cached after invalidation")
+	}
+
+	if err := p.Set(ctx, "key", &vault.Secret{Value: "new"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if secret, err := p.Get(ctx, "key"); err != nil || secret.Value != "new" {
+		t.Errorf("Get() = %v, %v; want new", secret, err)
+	}
+}
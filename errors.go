@@ -0,0 +1,202 @@
+package keyring
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/agentplexus/omnivault/vault"
+	dbus "github.com/godbus/dbus/v5"
+	zkeyring "github.com/zalando/go-keyring"
+)
+
+// ErrPreconditionFailed is returned when a conditional write is rejected
+// because the stored secret doesn't match the caller's expectation.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// PreconditionError describes a rejected conditional write.
+// It matches ErrPreconditionFailed with errors.Is, and also matches
+// vault.ErrAlreadyExists when the write required the secret to be absent.
+type PreconditionError struct {
+	// Path is the secret path of the rejected write.
+	Path string
+
+	// Expected is the ETag the caller required; empty means "must not exist".
+	Expected string
+
+	// Actual is the ETag of the stored secret; empty means it doesn't exist.
+	Actual string
+}
+
+// Error implements the error interface.
+func (e *PreconditionError) Error() string {
+	// ETags are left out: error messages tend to end up in logs.
+	switch {
+	case e.Expected == "":
+		return fmt.Sprintf("%v: secret already exists", ErrPreconditionFailed)
+	case e.Actual == "":
+		return fmt.Sprintf("%v: secret doesn't exist", ErrPreconditionFailed)
+	default:
+		return fmt.Sprintf("%v: secret has changed", ErrPreconditionFailed)
+	}
+}
+
+// Is reports whether the error matches the target.
+func (e *PreconditionError) Is(target error) bool {
+	switch target {
+	case ErrPreconditionFailed:
+		return true
+	case vault.ErrAlreadyExists:
+		return e.Expected == "" && e.Actual != ""
+	}
+	return false
+}
+
+// ErrLimitExceeded is returned when an operation would affect more secrets
+// than the caller's safety limit allows.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// Backend failure categories. A *BackendError matches its category with
+// errors.Is, along with the corresponding omnivault sentinel where one
+// exists: vault.ErrAccessDenied for a locked keyring and vault.ErrConnectionFailed
+// for an unavailable daemon. Access denied and invalid paths are reported
+// with vault.ErrAccessDenied and vault.ErrInvalidPath themselves.
+var (
+	// ErrKeyringLocked is returned when the keyring or collection holding the
+	// secret is locked and couldn't be unlocked.
+	ErrKeyringLocked = errors.New("keyring is locked")
+
+	// ErrDaemonUnavailable is returned when the OS keyring service can't be
+	// reached, e.g. no Secret Service provider is running on the session bus.
+	ErrDaemonUnavailable = errors.New("keyring daemon unavailable")
+
+	// ErrTooLarge is returned when a value exceeds the backend's size limit.
+	ErrTooLarge = errors.New("secret too large")
+)
+
+// BackendError is a classified failure reported by the OS keyring.
+type BackendError struct {
+	// Kind is the failure category: ErrKeyringLocked, vault.ErrAccessDenied,
+	// ErrDaemonUnavailable, ErrTooLarge or vault.ErrInvalidPath.
+	Kind error
+
+	// Hint suggests how to remedy the failure on the current platform.
+	Hint string
+
+	// Err is the error returned by the backend.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *BackendError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Kind, e.Err)
+}
+
+// Unwrap returns the failure category and the backend error.
+func (e *BackendError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// Is reports whether the error matches the omnivault sentinel that
+// corresponds to its category.
+func (e *BackendError) Is(target error) bool {
+	switch target {
+	case vault.ErrAccessDenied:
+		return e.Kind == ErrKeyringLocked
+	case vault.ErrConnectionFailed:
+		return e.Kind == ErrDaemonUnavailable
+	}
+	return false
+}
+
+// ErrorHint returns the remediation hint of the *BackendError in err's
+// chain, or "" if there is none.
+func ErrorHint(err error) string {
+	var backendErr *BackendError
+	if errors.As(err, &backendErr) {
+		return backendErr.Hint
+	}
+	return ""
+}
+
+// classify wraps a backend error in a *BackendError if it falls into a known
+// category. Missing secrets, context errors and unrecognized errors are
+// returned unchanged.
+func classify(err error) error {
+	if err == nil || errors.Is(err, zkeyring.ErrNotFound) || isContextError(err) {
+		return err
+	}
+	var backendErr *BackendError
+	if errors.As(err, &backendErr) {
+		return err
+	}
+
+	kind := classifyPlatform(err)
+	if kind == nil {
+		kind = classifySecretService(err)
+	}
+	if kind == nil {
+		return err
+	}
+	return &BackendError{Kind: kind, Hint: errorHint(kind), Err: err}
+}
+
+// classifySecretService categorizes errors from the Secret Service and the
+// D-Bus session bus.
+func classifySecretService(err error) error {
+	if errors.Is(err, zkeyring.ErrSetDataTooBig) {
+		return ErrTooLarge
+	}
+
+	if name, ok := dbusErrorName(err); ok {
+		switch {
+		case name == "org.freedesktop.Secret.Error.IsLocked":
+			return ErrKeyringLocked
+		case name == "org.freedesktop.DBus.Error.AccessDenied",
+			name == "org.freedesktop.DBus.Error.AuthFailed",
+			name == "org.freedesktop.DBus.Error.InteractiveAuthorizationRequired":
+			return vault.ErrAccessDenied
+		case name == "org.freedesktop.DBus.Error.InvalidArgs",
+			name == "org.freedesktop.DBus.Error.InvalidSignature":
+			return vault.ErrInvalidPath
+		case transientDBusErrors[name],
+			strings.HasPrefix(name, "org.freedesktop.DBus.Error.Spawn."):
+			return ErrDaemonUnavailable
+		}
+		return nil
+	}
+
+	msg := err.Error()
+	switch {
+	// go-keyring reports a dismissed unlock prompt with this message.
+	case strings.Contains(msg, "failed to unlock correct collection"):
+		return ErrKeyringLocked
+	case strings.Contains(msg, "couldn't determine address of session bus"),
+		strings.Contains(msg, "invalid bus address"):
+		return ErrDaemonUnavailable
+	}
+
+	if errors.Is(err, dbus.ErrClosed) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ENOENT) {
+		return ErrDaemonUnavailable
+	}
+	return nil
+}
+
+// errorHint returns the remediation hint for a failure category,
+// preferring the current platform's wording.
+func errorHint(kind error) string {
+	if hint, ok := platformHints[kind]; ok {
+		return hint
+	}
+	switch kind {
+	case ErrTooLarge:
+		return "store large values elsewhere and keep only a key or reference in the keyring"
+	case vault.ErrInvalidPath:
+		return "use a non-empty path of valid UTF-8 without control characters"
+	}
+	return ""
+}
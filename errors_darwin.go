@@ -0,0 +1,42 @@
+//go:build darwin
+
+package keyring
+
+import (
+	"errors"
+	"os/exec"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+// classifyPlatform categorizes errors from the security(1) command used to
+// access the macOS Keychain. Its exit status is the low byte of the
+// Security framework's OSStatus.
+func classifyPlatform(err error) error {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		if errors.Is(err, exec.ErrNotFound) {
+			return ErrDaemonUnavailable
+		}
+		return nil
+	}
+	switch exitErr.ExitCode() {
+	case 36: // errSecInteractionNotAllowed: the keychain is locked
+		return ErrKeyringLocked
+	case 51, 128: // errSecAuthFailed, userCanceledErr
+		return vault.ErrAccessDenied
+	case 50: // errSecNoSuchKeychain
+		return ErrDaemonUnavailable
+	case 42: // errSecDataTooLarge
+		return ErrTooLarge
+	}
+	return nil
+}
+
+// platformHints are the macOS remediation hints.
+var platformHints = map[error]string{
+	ErrKeyringLocked:      "unlock the login keychain, e.g. with `security unlock-keychain`; processes started over SSH can't show the unlock dialog",
+	vault.ErrAccessDenied: "allow this application to access the item in Keychain Access, or approve the access prompt",
+	ErrDaemonUnavailable:  "check that /usr/bin/security is present and the user has a default keychain (`security default-keychain`)",
+	ErrTooLarge:           "keychain items passed through security(1) are limited to about 4 KB; store large values elsewhere",
+}
@@ -0,0 +1,18 @@
+//go:build !darwin && !windows
+
+package keyring
+
+import "github.com/agentplexus/omnivault/vault"
+
+// classifyPlatform categorizes platform-specific errors. Secret Service
+// errors are handled by classifySecretService.
+func classifyPlatform(error) error {
+	return nil
+}
+
+// platformHints are the Secret Service remediation hints.
+var platformHints = map[error]string{
+	ErrKeyringLocked:      "unlock the login keyring, e.g. by logging in to a desktop session or with `gnome-keyring-daemon --unlock`",
+	vault.ErrAccessDenied: "check that the Secret Service provider allows this application to access the collection",
+	ErrDaemonUnavailable:  "start a Secret Service provider such as gnome-keyring-daemon or KWallet, and make sure DBUS_SESSION_BUS_ADDRESS is set",
+}
@@ -0,0 +1,104 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+
+	"github.com/agentplexus/omnivault/vault"
+	dbus "github.com/godbus/dbus/v5"
+	zkeyring "github.com/zalando/go-keyring"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind error // nil means the error is returned unchanged
+	}{
+		{"not found", zkeyring.ErrNotFound, nil},
+		{"canceled", context.Canceled, nil},
+		{"unknown", errors.New("boom"), nil},
+		{"too big", zkeyring.ErrSetDataTooBig, ErrTooLarge},
+		{"locked", dbus.Error{Name: "org.freedesktop.Secret.Error.IsLocked"}, ErrKeyringLocked},
+		{"prompt dismissed", errors.New("failed to unlock correct collection '/org/freedesktop/secrets/collection/login'"), ErrKeyringLocked},
+		{"access denied", &dbus.Error{Name: "org.freedesktop.DBus.Error.AccessDenied"}, vault.ErrAccessDenied},
+		{"invalid args", dbus.Error{Name: "org.freedesktop.DBus.Error.InvalidArgs"}, vault.ErrInvalidPath},
+		{"no provider", dbus.Error{Name: "org.freedesktop.DBus.Error.ServiceUnknown"}, ErrDaemonUnavailable},
+		{"no session bus", errors.New("dbus: couldn't determine address of session bus"), ErrDaemonUnavailable},
+		{"socket refused", fmt.Errorf("dial unix: %w", syscall.ECONNREFUSED), ErrDaemonUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classify(tt.err)
+			var backendErr *BackendError
+			if tt.kind == nil {
+				if got != tt.err {
+					t.Errorf("expected error to be unchanged, got %v", got)
+				}
+				return
+			}
+			if !errors.As(got, &backendErr) || backendErr.Kind != tt.kind {
+				t.Fatalf("expected kind %v, got %v", tt.kind, got)
+			}
+			if !errors.Is(got, tt.kind) {
+				t.Errorf("expected errors.Is(%v)", tt.kind)
+			}
+			if backendErr.Err.Error() != tt.err.Error() {
+				t.Errorf("expected the backend error to be preserved, got %v", backendErr.Err)
+			}
+		})
+	}
+}
+
+func TestBackendError_Sentinels(t *testing.T) {
+	locked := &BackendError{Kind: ErrKeyringLocked, Err: errors.New("locked")}
+	if !errors.Is(locked, vault.ErrAccessDenied) {
+		t.Error("expected a locked keyring to match vault.ErrAccessDenied")
+	}
+	unavailable := &BackendError{Kind: ErrDaemonUnavailable, Err: errors.New("down")}
+	if !errors.Is(unavailable, vault.ErrConnectionFailed) {
+		t.Error("expected an unavailable daemon to match vault.ErrConnectionFailed")
+	}
+	if errors.Is(unavailable, vault.ErrAccessDenied) {
+		t.Error("expected an unavailable daemon not to match vault.ErrAccessDenied")
+	}
+	tooLarge := classify(zkeyring.ErrSetDataTooBig)
+	if !errors.Is(tooLarge, zkeyring.ErrSetDataTooBig) {
+		t.Error("expected the backend error to remain matchable")
+	}
+}
+
+func TestProvider_ClassifiedErrors(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBackend()
+	useBackend(t, b)
+
+	p := New(Config{ServiceName: "test-classify"})
+	defer p.Close()
+
+	b.failWith(func(op, _, user string) error {
+		if user == "key" {
+			return dbus.Error{Name: "org.freedesktop.Secret.Error.IsLocked"}
+		}
+		return nil
+	})
+
+	_, err := p.Get(ctx, "key")
+	if !errors.Is(err, ErrKeyringLocked) || !errors.Is(err, vault.ErrAccessDenied) {
+		t.Errorf("expected a locked keyring error, got %v", err)
+	}
+	var vaultErr *vault.VaultError
+	if !errors.As(err, &vaultErr) || vaultErr.Op != "Get" || vaultErr.Path != "key" {
+		t.Errorf("expected a Get VaultError for key, got %v", err)
+	}
+	if ErrorHint(err) == "" {
+		t.Error("expected a remediation hint")
+	}
+	if n := b.count("Get", "key"); n != 1 {
+		t.Errorf("expected a locked keyring not to be retried, got %d attempts", n)
+	}
+}
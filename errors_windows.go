@@ -0,0 +1,31 @@
+//go:build windows
+
+package keyring
+
+import (
+	"errors"
+
+	"github.com/agentplexus/omnivault/vault"
+	"golang.org/x/sys/windows"
+)
+
+// classifyPlatform categorizes errors from the Windows Credential Manager.
+func classifyPlatform(err error) error {
+	switch {
+	case errors.Is(err, windows.ERROR_ACCESS_DENIED):
+		return vault.ErrAccessDenied
+	case errors.Is(err, windows.ERROR_NO_SUCH_LOGON_SESSION):
+		return ErrDaemonUnavailable
+	case errors.Is(err, windows.ERROR_INVALID_PARAMETER),
+		errors.Is(err, windows.ERROR_BAD_USERNAME):
+		return vault.ErrInvalidPath
+	}
+	return nil
+}
+
+// platformHints are the Windows remediation hints.
+var platformHints = map[error]string{
+	vault.ErrAccessDenied: "run the process as the user that owns the credential",
+	ErrDaemonUnavailable:  "Credential Manager needs a logon session with a loaded user profile; services may need to run as a user account",
+	ErrTooLarge:           "Credential Manager limits credentials to 2560 bytes; store large values elsewhere",
+}
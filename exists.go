@@ -0,0 +1,47 @@
+package keyring
+
+import (
+	"context"
+)
+
+// existenceChecker is implemented by backends that can tell whether a value
+// is stored without retrieving it, e.g. by searching item attributes.
+type existenceChecker interface {
+	// exists reports whether a value is stored for user under service.
+	exists(service, user string) (bool, error)
+}
+
+// probe reports whether a value is stored at path, using the cache, then
+// the backend's metadata-only lookup, and a full read only if the backend
+// has none. Concurrent probes of the same path share one backend call.
+func (p *Provider) probe(ctx context.Context, path string) (bool, error) {
+	checker, ok := p.backend.(existenceChecker)
+	if !ok {
+		_, err := p.cachedRead(ctx, path)
+		return err == nil, err
+	}
+	if p.cache != nil {
+		if _, found, hit := p.cache.get(path); hit {
+			return found, nil
+		}
+	}
+
+	found, err := p.share(ctx, existsKey(path), func(ctx context.Context) (any, error) {
+		var found bool
+		err := p.retry(ctx, "Exists", path, func() (err error) {
+			found, err = call(ctx, func() (bool, error) {
+				return checker.exists(p.config.ServiceName, p.backendKey(path))
+			})
+			return err
+		})
+		return found, classify(err)
+	})
+	exists, _ := found.(bool)
+	return exists, err
+}
+
+// existsKey returns the key under which probes of path are shared, distinct
+// from that of reads.
+func existsKey(path string) string {
+	return "\x00exists\x00" + path
+}
@@ -0,0 +1,22 @@
+//go:build darwin
+
+package keyring
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// errSecItemNotFound is the exit status of security(1) when no item matches.
+const errSecItemNotFound = 44
+
+// exists looks the item up without -w, so security(1) prints only its
+// attributes and the Keychain doesn't ask for access to the secret.
+func (osBackend) exists(service, user string) (bool, error) {
+	err := exec.Command("/usr/bin/security", "find-generic-password", "-s", service, "-a", user).Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == errSecItemNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
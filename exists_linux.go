@@ -0,0 +1,59 @@
+//go:build linux
+
+package keyring
+
+import (
+	"context"
+	"errors"
+
+	dbus "github.com/godbus/dbus/v5"
+	zkeyring "github.com/zalando/go-keyring"
+)
+
+// exists searches the login collection by attributes. Unlike Get it neither
+// unlocks the collection nor opens a session to transfer the secret.
+func (osBackend) exists(service, user string) (bool, error) {
+	ctx := context.Background()
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return false, err
+	}
+	collection, err := loginCollection(ctx, conn)
+	if err != nil {
+		return false, err
+	}
+
+	var items []dbus.ObjectPath
+	err = conn.Object(secretServiceName, collection).CallWithContext(ctx,
+		secretCollectionIface+".SearchItems", 0, itemAttributes(service, user)).
+		Store(&items)
+	if err != nil {
+		return false, err
+	}
+	return len(items) > 0, nil
+}
+
+// exists searches the configured collection by attributes, without
+// unlocking it or transferring the secret. A collection that doesn't exist
+// yet holds nothing, so it isn't created.
+func (b *secretServiceBackend) exists(service, user string) (bool, error) {
+	ctx := context.Background()
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return false, err
+	}
+	b.mu.Lock()
+	collection := b.path
+	b.mu.Unlock()
+	if collection == "" {
+		if collection, err = findCollection(ctx, conn, b.name); err != nil || collection == "" {
+			return false, err
+		}
+	}
+
+	_, err = b.findItem(ctx, conn, collection, service, user)
+	if errors.Is(err, zkeyring.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,123 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+// checkingBackend is a memoryBackend with a metadata-only existence check.
+type checkingBackend struct {
+	*memoryBackend
+}
+
+func (c checkingBackend) exists(service, user string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.record("Exists", service, user); err != nil {
+		return false, err
+	}
+	_, ok := c.store[service][user]
+	return ok, nil
+}
+
+func TestExistsWithoutRead(t *testing.T) {
+	b := newMemoryBackend()
+	useBackend(t, checkingBackend{b})
+	ctx := context.Background()
+	p := New(Config{ServiceName: "exists-test"})
+
+	if err := p.Set(ctx, "present", &vault.Secret{Value: "v"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if ok, err := p.Exists(ctx, "present"); err != nil || !ok {
+		t.Errorf("Exists(present) = %v, %v; want true", ok, err)
+	}
+	if ok, err := p.Exists(ctx, "missing"); err != nil || ok {
+		t.Errorf("Exists(missing) = %v, %v; want false", ok, err)
+	}
+	if n := b.count("Get", "present") + b.count("Get", "missing"); n != 0 {
+		t.Errorf("backend Get called %d times, want 0", n)
+	}
+	if n := b.count("Exists", "present"); n != 1 {
+		t.Errorf("backend exists called %d times, want 1", n)
+	}
+}
+
+func TestExistsCheckError(t *testing.T) {
+	b := newMemoryBackend()
+	useBackend(t, checkingBackend{b})
+	p := New(Config{ServiceName: "exists-test"})
+
+	b.failWith(func(op, service, user string) error {
+		if op == "Exists" {
+			return ErrKeyringLocked
+		}
+		return nil
+	})
+	_, err := p.Exists(context.Background(), "any")
+	var verr *vault.VaultError
+	if !errors.As(err, &verr) || !errors.Is(err, ErrKeyringLocked) {
+		t.Errorf("Exists() error = %v, want VaultError wrapping ErrKeyringLocked", err)
+	}
+}
+
+func TestExistsCheckUsesCacheAndObfuscation(t *testing.T) {
+	b := newMemoryBackend()
+	useBackend(t, checkingBackend{b})
+	ctx := context.Background()
+	p := New(Config{ServiceName: "exists-test", PathKey: NewPathKey(), Cache: CacheConfig{TTL: time.Minute}})
+
+	if err := p.Set(ctx, "db/password", &vault.Secret{Value: "v"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if _, err := p.Get(ctx, "db/password"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	// Served from the cache filled by Get.
+	if ok, err := p.Exists(ctx, "db/password"); err != nil || !ok {
+		t.Errorf("Exists() = %v, %v; want true", ok, err)
+	}
+	stored := p.backendKey("db/password")
+	if n := b.count("Exists", stored); n != 0 {
+		t.Errorf("backend exists called %d times despite a cached value, want 0", n)
+	}
+
+	p.invalidate("db/password")
+	if ok, err := p.Exists(ctx, "db/password"); err != nil || !ok {
+		t.Errorf("Exists() after invalidation = %v, %v; want true", ok, err)
+	}
+	if n := b.count("Exists", stored); n != 1 {
+		t.Errorf("backend exists called %d times for the obfuscated key, want 1", n)
+	}
+}
+
+func TestExistsCheckFallbackProfile(t *testing.T) {
+	b := newMemoryBackend()
+	useBackend(t, checkingBackend{b})
+	ctx := context.Background()
+	config := Config{
+		ServiceName: "exists-test",
+		Profiles: map[string]Profile{
+			"dev":  {Prefix: "dev", Fallback: "base"},
+			"base": {Prefix: "base"},
+		},
+		Profile: "base",
+	}
+	base := New(config)
+	if err := base.Set(ctx, "shared", &vault.Secret{Value: "v"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	config.Profile = "dev"
+	dev := New(config)
+	if ok, err := dev.Exists(ctx, "shared"); err != nil || !ok {
+		t.Errorf("Exists() through fallback = %v, %v; want true", ok, err)
+	}
+	if n := b.count("Get", "base/shared"); n != 0 {
+		t.Errorf("backend Get called %d times, want 0", n)
+	}
+}
@@ -0,0 +1,147 @@
+package keyring
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/agentplexus/omnivault/vault"
+	zkeyring "github.com/zalando/go-keyring"
+)
+
+// healthKey is the key of the canary secret written by Health.
+const healthKey = "__omnivault_health__"
+
+// HealthReport describes the state of the OS keyring as probed by Health.
+type HealthReport struct {
+	// Backend is the name of the OS keyring backend, as returned by Backend.
+	Backend string
+
+	// Reachable reports whether the keyring service answered.
+	Reachable bool
+
+	// Unlocked reports whether the keyring holding the secrets is unlocked.
+	Unlocked bool
+
+	// Writable reports whether a value could be written and read back.
+	Writable bool
+
+	// MaxValueSize is the largest value in bytes the backend accepts for a
+	// secret, or 0 if it has no fixed limit. On macOS it is approximate, as
+	// the limit depends on the lengths of the service name and path.
+	MaxValueSize int
+
+	// NativeEnumeration reports whether List enumerates secrets through the
+	// backend itself. It is always false: no backend can be asked for its
+	// entries, so List always goes through the index.
+	NativeEnumeration bool
+
+	// Latency is the round-trip time of reading the canary secret.
+	Latency time.Duration
+
+	// Err is the first failure encountered by the probe, or nil.
+	Err error
+}
+
+// Healthy reports whether the keyring is reachable, unlocked and writable.
+func (r *HealthReport) Healthy() bool {
+	return r.Reachable && r.Unlocked && r.Writable
+}
+
+// Health probes the OS keyring by writing, reading back and deleting a
+// canary secret under a reserved key, and reports what it found.
+//
+// The report is always returned. The error is nil only if the keyring is
+// healthy; otherwise it wraps the first failure, classified as described
+// for BackendError.
+func (p *Provider) Health(ctx context.Context) (*HealthReport, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	report := &HealthReport{
+		Backend:      p.Backend(),
+		MaxValueSize: p.maxValueSize(),
+	}
+	if p.closed {
+		report.Err = vault.ErrClosed
+		return report, vault.NewVaultError("Health", "", p.Name(), vault.ErrClosed)
+	}
+
+	unlock := p.locks.lock(healthKey)
+	defer unlock()
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	canary := newCanary()
+	if err := p.write(ctx, healthKey, canary); err != nil {
+		report.Err = err
+		// Tell a read-only or denied keyring apart from an unreachable one.
+		_, readErr := p.read(ctx, healthKey)
+		if readErr == nil || errors.Is(readErr, zkeyring.ErrNotFound) {
+			report.Reachable = true
+			report.Unlocked = true
+		} else {
+			report.Reachable = !errors.Is(readErr, ErrDaemonUnavailable) && !isContextError(readErr)
+		}
+		return report, vault.NewVaultError("Health", "", p.Name(), err)
+	}
+	report.Reachable = true
+	report.Unlocked = true
+
+	start := time.Now()
+	value, err := p.read(ctx, healthKey)
+	report.Latency = time.Since(start)
+	switch {
+	case err != nil:
+		report.Err = err
+	case value != canary:
+		report.Err = fmt.Errorf("canary read back as %d bytes, wrote %d", len(value), len(canary))
+	default:
+		report.Writable = true
+	}
+
+	cleanup, cancel := p.detach(ctx)
+	defer cancel()
+	if err := p.remove(cleanup, healthKey); err != nil && !errors.Is(err, zkeyring.ErrNotFound) && report.Err == nil {
+		report.Err = err
+		report.Writable = false
+	}
+
+	if report.Err != nil {
+		return report, vault.NewVaultError("Health", "", p.Name(), report.Err)
+	}
+	return report, nil
+}
+
+// maxValueSize returns the largest value the OS backend accepts, or 0 if
+// it has no fixed limit.
+func (p *Provider) maxValueSize() int {
+	switch runtime.GOOS {
+	case "windows":
+		// Credential Manager limits credential blobs to 5*512 bytes.
+		return 2560
+	case "darwin":
+		// go-keyring base64-encodes values into a security(1) command line
+		// limited to 4096 bytes.
+		overhead := len("add-generic-password -U -s '' -a '' -w 'go-keyring-base64:'\n") + len(p.config.ServiceName)
+		return (4096 - overhead) / 4 * 3
+	default:
+		return 0
+	}
+}
+
+// newCanary returns a random value for the health check canary.
+func newCanary() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return "canary-" + hex.EncodeToString(b[:])
+}
+
+// isContextError reports whether err was caused by a done context.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
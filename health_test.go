@@ -0,0 +1,117 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/agentplexus/omnivault/vault"
+	dbus "github.com/godbus/dbus/v5"
+	zkeyring "github.com/zalando/go-keyring"
+)
+
+func TestProvider_Health(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBackend()
+	useBackend(t, b)
+
+	p := New(Config{ServiceName: "test-health"})
+	defer p.Close()
+
+	report, err := p.Health(ctx)
+	if err != nil {
+		t.Fatalf("Health failed: %v", err)
+	}
+	if !report.Healthy() || report.Err != nil {
+		t.Errorf("expected a healthy report, got %+v", report)
+	}
+	if report.Backend != p.Backend() {
+		t.Errorf("expected backend %q, got %q", p.Backend(), report.Backend)
+	}
+	if report.NativeEnumeration {
+		t.Error("expected NativeEnumeration to be false, as List uses the index")
+	}
+	if _, err := b.Get("test-health", healthKey); !errors.Is(err, zkeyring.ErrNotFound) {
+		t.Errorf("expected canary to be removed, got %v", err)
+	}
+	if list, _ := p.List(ctx, ""); len(list) != 0 {
+		t.Errorf("expected canary not to be indexed, got %v", list)
+	}
+}
+
+func TestProvider_Health_Failures(t *testing.T) {
+	locked := dbus.Error{Name: "org.freedesktop.Secret.Error.IsLocked"}
+	unavailable := dbus.Error{Name: "org.freedesktop.DBus.Error.ServiceUnknown"}
+	denied := dbus.Error{Name: "org.freedesktop.DBus.Error.AccessDenied"}
+
+	tests := []struct {
+		name      string
+		fail      func(op, service, user string) error
+		reachable bool
+		unlocked  bool
+		want      error
+	}{
+		{
+			name:      "locked",
+			fail:      func(string, string, string) error { return locked },
+			reachable: true,
+			want:      ErrKeyringLocked,
+		},
+		{
+			name: "unavailable",
+			fail: func(string, string, string) error { return unavailable },
+			want: ErrDaemonUnavailable,
+		},
+		{
+			name: "read-only",
+			fail: func(op, _, _ string) error {
+				if op == "Set" {
+					return denied
+				}
+				return nil
+			},
+			reachable: true,
+			unlocked:  true,
+			want:      vault.ErrAccessDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newMemoryBackend()
+			useBackend(t, b)
+
+			p := New(Config{ServiceName: "test-health-fail", Retry: RetryPolicy{MaxAttempts: 1}})
+			defer p.Close()
+			b.failWith(tt.fail)
+
+			report, err := p.Health(context.Background())
+			if !errors.Is(err, tt.want) || !errors.Is(report.Err, tt.want) {
+				t.Errorf("expected %v, got %v (report %v)", tt.want, err, report.Err)
+			}
+			if report.Reachable != tt.reachable || report.Unlocked != tt.unlocked || report.Writable {
+				t.Errorf("unexpected report %+v", report)
+			}
+		})
+	}
+}
+
+func TestProvider_Health_Closed(t *testing.T) {
+	p := New(Config{ServiceName: "test-health-closed"})
+	_ = p.Close()
+
+	report, err := p.Health(context.Background())
+	if !errors.Is(err, vault.ErrClosed) || report.Healthy() {
+		t.Errorf("expected ErrClosed and an unhealthy report, got %+v, %v", report, err)
+	}
+}
+
+func TestProvider_Health_ReservedKey(t *testing.T) {
+	p := New(Config{ServiceName: "test-health-reserved"})
+	defer p.Close()
+
+	err := p.Rename(context.Background(), "anything", healthKey)
+	if !errors.Is(err, vault.ErrInvalidPath) {
+		t.Errorf("expected the canary key to be reserved, got %v", err)
+	}
+}
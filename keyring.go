@@ -30,18 +30,32 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"log/slog"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/agentplexus/omnivault/vault"
 	zkeyring "github.com/zalando/go-keyring"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
 	// DefaultServiceName is the default service name used if none is provided.
 	DefaultServiceName = "omnivault"
 
+	// DefaultBatchConcurrency is the default number of parallel backend
+	// operations used by batch methods.
+	DefaultBatchConcurrency = 4
+
+	// SessionCollection selects the Secret Service's in-memory session
+	// collection, whose secrets vanish when the user logs out.
+	SessionCollection = "session"
+
 	// indexKey is the key used to store the list of all secret keys.
 	// This enables the List() functionality since OS keyrings don't support enumeration.
 	indexKey = "__omnivault_index__"
@@ -60,19 +74,139 @@ type Config struct {
 	// Default: false
 	JSONFormat bool
 
+	// Collection selects the Secret Service collection secrets are stored in.
+	// Empty uses the default collection (usually "login"). SessionCollection
+	// uses the in-memory session collection. Any other value names a
+	// collection by alias, object name or label; it is created on first use
+	// if it doesn't exist, which usually prompts for its password.
+	// Only used on Linux.
+	// Default: "" (default collection)
+	Collection string
+
+	// CollectionLabel is the label of a collection created for Collection.
+	// Default: Collection
+	CollectionLabel string
+
+	// BatchConcurrency is the maximum number of backend operations that
+	// GetMany, SetMany and DeleteMany run in parallel.
+	// Default: 4
+	BatchConcurrency int
+
+	// WatchInterval is how often Watch polls for changes when the backend
+	// can't deliver change notifications natively.
+	// Default: 5s
+	WatchInterval time.Duration
+
+	// DefaultTimeout bounds every operation whose context has no deadline.
+	// Secret Service calls can block indefinitely on an unlock prompt or an
+	// unresponsive D-Bus daemon; a timed-out call is abandoned and returns
+	// an error wrapping context.DeadlineExceeded.
+	// Default: 0 (no timeout)
+	DefaultTimeout time.Duration
+
+	// Retry controls how backend calls that fail transiently, e.g. right
+	// after login or while the keyring daemon restarts, are retried.
+	// Default: 3 attempts with exponential backoff from 100ms
+	Retry RetryPolicy
+
+	// OnRetry is called before each retry of a failed backend call.
+	// If nil, retries are not reported.
+	OnRetry func(attempt RetryAttempt)
+
+	// PathRules controls how paths are validated and canonicalized. Every
+	// operation applies them before touching the keyring. Secrets stored
+	// under paths the rules change are moved by MigratePaths.
+	// Default: all rules enabled (see PathRules)
+	PathRules PathRules
+
+	// PathKey, if set, hides secret paths from anyone browsing the keyring.
+	// Every key is stored as an HMAC of its path under PathKey, and the
+	// index and transaction journal are encrypted with a key derived from
+	// it, so the real paths only exist inside the provider. It must be at
+	// least MinPathKeySize bytes, and the same key must be used every time
+	// or the stored secrets can't be found. See NewPathKey.
+	// Default: nil (paths are stored in the clear)
+	PathKey []byte
+
+	// Profiles maps profile names, such as "dev", "staging" and "prod", to
+	// where their secrets are stored. If empty, profiles are disabled.
+	Profiles map[string]Profile
+
+	// Profile is the name of the active profile, used when the profile
+	// environment variable is not set.
+	Profile string
+
+	// ProfileEnv is the environment variable that selects the active
+	// profile, overriding Profile. It is only read if Profiles is set.
+	// Default: "OMNIVAULT_PROFILE"
+	ProfileEnv string
+
+	// Cache configures an in-process read cache in front of the keyring.
+	// Default: disabled
+	Cache CacheConfig
+
+	// Middleware wraps every operation that reads or changes secrets, in
+	// order: the first is outermost. Lookups in fallback profiles are part
+	// of the operation that made them. See Middleware.
+	// Default: none
+	Middleware []Middleware
+
+	// TracerProvider, if set, records a span for every operation passing
+	// through the middleware chain and for every index load and save.
+	// Default: nil (no tracing)
+	TracerProvider trace.TracerProvider
+
+	// MeterProvider, if set, records operation latency, errors by
+	// category, read cache activity and the size of the index.
+	// Default: nil (no metrics)
+	MeterProvider metric.MeterProvider
+
+	// HashTelemetryPaths records a hash of each path in spans instead of
+	// the path itself. Paths are always hashed, with PathKey, when PathKey
+	// is set.
+	// Default: false
+	HashTelemetryPaths bool
+
+	// Logger, if set, receives debug logs about backend selection, profile
+	// fallbacks, index loads and saves, retries and values that aren't in
+	// the expected format, and warnings about unusable configuration.
+	// Secret values are never logged, and secrets passed to the logger are
+	// redacted (see NewRedactingHandler).
+	// Default: nil (no logging)
+	Logger *slog.Logger
+
 	// OnIndexError is called when an error occurs during index operations.
 	// Index operations are used to track stored keys for List() functionality.
 	// These errors are non-fatal (Get/Set/Delete still work) but may cause
 	// List() to return incomplete results.
+	// Problems with the transaction journal are also reported here, with op
+	// "journal" or "recover".
 	// If nil, index errors are silently ignored.
 	OnIndexError func(op string, err error)
 }
 
 // Provider implements vault.Vault using OS credential stores.
 type Provider struct {
-	config Config
-	mu     sync.RWMutex
-	closed bool
+	config    Config
+	backend   backend
+	profile   string             // active profile name
+	keyPrefix string             // prepended to every key by the active profile
+	fallback  *Provider          // profile reads fall back to, or nil
+	paths     *pathCipher        // obfuscates keys when Config.PathKey is set
+	cache     *secretCache       // nil unless Config.Cache enables it
+	telemetry *telemetry         // nil unless a tracer or meter provider is set
+	log       *slog.Logger       // never nil; discards records unless Config.Logger is set
+	reads     singleflight.Group // coalesces concurrent reads of a path
+	locks     pathLocks          // serializes writes to a path
+	journalMu sync.Mutex         // serializes commits, which share the journal
+	etagMu    sync.Mutex         // guards etagKey and etagRetry
+	etagKey   []byte             // HMAC key of ETags, loaded on first use
+	etagRetry time.Time          // when reads may try loading etagKey again
+	recovered atomic.Bool        // set once the journal has been recovered
+	indexMu   sync.Mutex         // serializes index updates
+	mu        sync.RWMutex
+	closed    bool
+	done      chan struct{} // closed by Close
 }
 
 // New creates a new keyring provider with the given configuration.
@@ -80,7 +214,14 @@ func New(config Config) *Provider {
 	if config.ServiceName == "" {
 		config.ServiceName = DefaultServiceName
 	}
-	return &Provider{config: config}
+	if config.BatchConcurrency <= 0 {
+		config.BatchConcurrency = DefaultBatchConcurrency
+	}
+	if config.WatchInterval <= 0 {
+		config.WatchInterval = DefaultWatchInterval
+	}
+	config.Retry = config.Retry.withDefaults()
+	return newProvider(config, selectProfile(config), make(map[string]bool))
 }
 
 // NewWithServiceName creates a new keyring provider with the specified service name.
@@ -96,61 +237,122 @@ func (p *Provider) Get(ctx context.Context, path string) (*vault.Secret, error)
 	if p.closed {
 		return nil, vault.NewVaultError("Get", path, p.Name(), vault.ErrClosed)
 	}
+	path, err := p.canonical("Get", path)
+	if err != nil {
+		return nil, err
+	}
 
-	value, err := zkeyring.Get(p.config.ServiceName, path)
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := p.handle(ctx, &Request{Op: "Get", Path: path}, func(ctx context.Context, req *Request) (*Response, error) {
+		secret, err := p.get(ctx, "Get", path)
+		return &Response{Secret: secret}, err
+	})
+	return resp.Secret, err
+}
+
+// get reads and decodes a secret. The caller must hold p.mu.
+func (p *Provider) get(ctx context.Context, op, path string) (*vault.Secret, error) {
+	value, err := p.cachedRead(ctx, path)
 	if err != nil {
 		if errors.Is(err, zkeyring.ErrNotFound) {
-			return nil, vault.NewVaultError("Get", path, p.Name(), vault.ErrSecretNotFound)
+			if p.fallback != nil {
+				p.logFallback(ctx, path)
+				return p.fallbackGet(ctx, op, path)
+			}
+			return nil, vault.NewVaultError(op, path, p.Name(), vault.ErrSecretNotFound)
 		}
-		return nil, vault.NewVaultError("Get", path, p.Name(), err)
+		return nil, vault.NewVaultError(op, path, p.Name(), err)
 	}
+	return p.decode(path, value, p.readETag(ctx, path, value)), nil
+}
 
-	secret := &vault.Secret{
-		Metadata: vault.Metadata{
-			Provider: p.Name(),
-			Path:     path,
-		},
-	}
+// decode converts a stored value into a secret.
+// The secret's metadata always reflects where it was read from, along with
+// etag, the ETag of the stored value, unless it is "".
+func (p *Provider) decode(path, value, etag string) *vault.Secret {
+	secret := &vault.Secret{}
 
 	if p.config.JSONFormat {
 		if err := json.Unmarshal([]byte(value), secret); err != nil {
 			// Fall back to plain value if JSON parsing fails
-			secret.Value = value
+			p.log.Debug("keyring: stored value is not JSON, returning it as a plain value", p.logPath(path))
+			secret = &vault.Secret{Value: value}
 		}
 	} else {
 		secret.Value = value
 	}
 
-	return secret, nil
+	secret.Metadata.Provider = p.Name()
+	secret.Metadata.Path = path
+	if etag != "" {
+		if secret.Metadata.Extra == nil {
+			secret.Metadata.Extra = make(map[string]any, 1)
+		}
+		secret.Metadata.Extra[MetadataETag] = etag
+	}
+
+	return secret
+}
+
+// encode converts a secret into the value stored in the keyring.
+func (p *Provider) encode(secret *vault.Secret) (string, error) {
+	if p.config.JSONFormat {
+		data, err := json.Marshal(withoutETag(secret))
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+	return secret.String(), nil
 }
 
 // Set stores a secret in the OS keyring.
 func (p *Provider) Set(ctx context.Context, path string, secret *vault.Secret) error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+	if err := p.ensureRecovered(ctx); err != nil {
+		return vault.NewVaultError("Set", path, p.Name(), err)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 
 	if p.closed {
 		return vault.NewVaultError("Set", path, p.Name(), vault.ErrClosed)
 	}
+	path, err := p.canonical("Set", path)
+	if err != nil {
+		return err
+	}
+	unlock := p.locks.lock(path)
+	defer unlock()
 
-	var value string
-	if p.config.JSONFormat {
-		data, err := json.Marshal(secret)
-		if err != nil {
-			return vault.NewVaultError("Set", path, p.Name(), err)
-		}
-		value = string(data)
-	} else {
-		value = secret.String()
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	_, err = p.handle(ctx, &Request{Op: "Set", Path: path, Secret: secret}, func(ctx context.Context, req *Request) (*Response, error) {
+		return nil, p.put(ctx, "Set", path, secret)
+	})
+	return err
+}
+
+// put encodes and stores a secret and records it in the index. The caller
+// must hold p.mu for writing, or for reading along with the lock of path.
+func (p *Provider) put(ctx context.Context, op, path string, secret *vault.Secret) error {
+	value, err := p.encode(secret)
+	if err != nil {
+		return vault.NewVaultError(op, path, p.Name(), err)
 	}
 
-	if err := zkeyring.Set(p.config.ServiceName, path, value); err != nil {
-		return vault.NewVaultError("Set", path, p.Name(), err)
+	if err := p.write(ctx, path, value); err != nil {
+		return vault.NewVaultError(op, path, p.Name(), err)
 	}
 
 	// Update the index for List() support
-	if path != indexKey {
-		p.addToIndex(path)
+	if !isReservedKey(path) {
+		ctx, cancel := p.detach(ctx)
+		defer cancel()
+		p.updateIndex(ctx, []string{path}, nil)
 	}
 
 	return nil
@@ -158,14 +360,36 @@ func (p *Provider) Set(ctx context.Context, path string, secret *vault.Secret) e
 
 // Delete removes a secret from the OS keyring.
 func (p *Provider) Delete(ctx context.Context, path string) error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+	if err := p.ensureRecovered(ctx); err != nil {
+		return vault.NewVaultError("Delete", path, p.Name(), err)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 
 	if p.closed {
 		return vault.NewVaultError("Delete", path, p.Name(), vault.ErrClosed)
 	}
+	path, err := p.canonical("Delete", path)
+	if err != nil {
+		return err
+	}
+	unlock := p.locks.lock(path)
+	defer unlock()
 
-	if err := zkeyring.Delete(p.config.ServiceName, path); err != nil {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	_, err = p.handle(ctx, &Request{Op: "Delete", Path: path}, func(ctx context.Context, req *Request) (*Response, error) {
+		return nil, p.delete(ctx, path)
+	})
+	return err
+}
+
+// delete removes a secret and drops it from the index. The caller must hold
+// p.mu for reading along with the lock of path.
+func (p *Provider) delete(ctx context.Context, path string) error {
+	if err := p.remove(ctx, path); err != nil {
 		if errors.Is(err, zkeyring.ErrNotFound) {
 			return nil // Already deleted
 		}
@@ -173,14 +397,18 @@ func (p *Provider) Delete(ctx context.Context, path string) error {
 	}
 
 	// Update the index
-	if path != indexKey {
-		p.removeFromIndex(path)
+	if !isReservedKey(path) {
+		ctx, cancel := p.detach(ctx)
+		defer cancel()
+		p.updateIndex(ctx, nil, []string{path})
 	}
 
 	return nil
 }
 
-// Exists checks if a secret exists in the OS keyring.
+// Exists checks if a secret exists in the OS keyring. Where the keyring
+// supports it, only the item's attributes are looked up and the secret
+// itself is not retrieved.
 func (p *Provider) Exists(ctx context.Context, path string) (bool, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
@@ -188,15 +416,27 @@ func (p *Provider) Exists(ctx context.Context, path string) (bool, error) {
 	if p.closed {
 		return false, vault.NewVaultError("Exists", path, p.Name(), vault.ErrClosed)
 	}
-
-	_, err := zkeyring.Get(p.config.ServiceName, path)
+	path, err := p.canonical("Exists", path)
 	if err != nil {
-		if errors.Is(err, zkeyring.ErrNotFound) {
-			return false, nil
-		}
-		return false, vault.NewVaultError("Exists", path, p.Name(), err)
+		return false, err
 	}
-	return true, nil
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := p.handle(ctx, &Request{Op: "Exists", Path: path}, func(ctx context.Context, req *Request) (*Response, error) {
+		found, err := p.probe(ctx, path)
+		switch {
+		case err != nil && !errors.Is(err, zkeyring.ErrNotFound):
+			return nil, vault.NewVaultError("Exists", path, p.Name(), err)
+		case !found && p.fallback != nil:
+			p.logFallback(ctx, path)
+			found, err = p.fallback.Exists(ctx, path)
+			return &Response{Exists: found}, err
+		}
+		return &Response{Exists: found}, nil
+	})
+	return resp.Exists, err
 }
 
 // List returns all secret paths matching the prefix.
@@ -209,15 +449,28 @@ func (p *Provider) List(ctx context.Context, prefix string) ([]string, error) {
 	if p.closed {
 		return nil, vault.NewVaultError("List", prefix, p.Name(), vault.ErrClosed)
 	}
+	prefix, err := p.canonicalPrefix("List", prefix)
+	if err != nil {
+		return nil, err
+	}
 
-	index := p.loadIndex()
-	var results []string
-	for _, key := range index {
-		if strings.HasPrefix(key, prefix) {
-			results = append(results, key)
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := p.handle(ctx, &Request{Op: "List", Path: prefix}, func(ctx context.Context, req *Request) (*Response, error) {
+		index := p.loadIndex(ctx)
+		if err := ctx.Err(); err != nil {
+			return nil, vault.NewVaultError("List", prefix, p.Name(), err)
 		}
-	}
-	return results, nil
+		var results []string
+		for _, key := range index {
+			if strings.HasPrefix(key, prefix) {
+				results = append(results, key)
+			}
+		}
+		return &Response{Paths: results}, nil
+	})
+	return resp.Paths, err
 }
 
 // Name returns the provider name.
@@ -233,18 +486,34 @@ func (p *Provider) Capabilities() vault.Capabilities {
 		Delete:     true,
 		List:       true, // Via internal index
 		MultiField: p.config.JSONFormat,
+		Batch:      true,
+		Watch:      true,
 	}
 }
 
-// Close marks the provider as closed.
+// Close marks the provider, and the providers of its fallback profiles, as
+// closed.
 func (p *Provider) Close() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	p.closed = true
+	if !p.closed {
+		p.closed = true
+		close(p.done)
+		if p.cache != nil {
+			p.cache.purge()
+		}
+		if p.telemetry != nil {
+			p.telemetry.close()
+		}
+	}
+	if p.fallback != nil {
+		return p.fallback.Close()
+	}
 	return nil
 }
 
-// ServiceName returns the configured service name.
+// ServiceName returns the service name secrets are stored under, which the
+// active profile may override.
 func (p *Provider) ServiceName() string {
 	return p.config.ServiceName
 }
@@ -264,64 +533,105 @@ func (p *Provider) Backend() string {
 }
 
 // loadIndex loads the list of stored keys from the index.
-func (p *Provider) loadIndex() []string {
-	value, err := zkeyring.Get(p.config.ServiceName, indexKey)
+func (p *Provider) loadIndex(ctx context.Context) (index []string) {
+	ctx, span := p.telemetry.startIndexSpan(ctx, "load")
+	var err error
+	defer func() { p.telemetry.recordIndex(ctx, span, len(index), err) }()
+
+	value, err := p.read(ctx, indexKey)
 	if err != nil {
 		// Only report non-"not found" errors (index may not exist yet)
 		if !errors.Is(err, zkeyring.ErrNotFound) {
 			p.reportIndexError("load", err)
+		} else {
+			p.log.DebugContext(ctx, "keyring: no index yet")
+			err = nil
 		}
 		return nil
 	}
-	var index []string
-	if err := json.Unmarshal([]byte(value), &index); err != nil {
+	data, err := p.openRecord(indexKey, value)
+	if err != nil {
+		p.reportIndexError("decrypt", err)
+		return nil
+	}
+	if err = json.Unmarshal(data, &index); err != nil {
 		p.reportIndexError("unmarshal", err)
 		return nil
 	}
+	p.log.DebugContext(ctx, "keyring: index loaded", slog.Int("size", len(index)))
 	return index
 }
 
 // saveIndex saves the list of stored keys to the index.
-func (p *Provider) saveIndex(index []string) {
+func (p *Provider) saveIndex(ctx context.Context, index []string) {
+	ctx, span := p.telemetry.startIndexSpan(ctx, "save")
+	var err error
+	defer func() { p.telemetry.recordIndex(ctx, span, len(index), err) }()
+
 	data, err := json.Marshal(index)
 	if err != nil {
 		p.reportIndexError("marshal", err)
 		return
 	}
-	if err := zkeyring.Set(p.config.ServiceName, indexKey, string(data)); err != nil {
+	if err = p.write(ctx, indexKey, p.sealRecord(indexKey, data)); err != nil {
 		p.reportIndexError("save", err)
+		return
 	}
+	p.log.DebugContext(ctx, "keyring: index saved", slog.Int("size", len(index)))
 }
 
-// reportIndexError calls the OnIndexError callback if configured.
+// reportIndexError logs an index error and calls the OnIndexError callback
+// if configured.
 func (p *Provider) reportIndexError(op string, err error) {
+	p.log.Warn("keyring: index operation failed", slog.String("op", op), slog.Any("error", err))
 	if p.config.OnIndexError != nil {
 		p.config.OnIndexError(op, err)
 	}
 }
 
-// addToIndex adds a key to the index.
-func (p *Provider) addToIndex(key string) {
-	index := p.loadIndex()
+// updateIndex adds and removes keys with a single index load and save.
+// The index is left untouched if it already reflects the changes.
+func (p *Provider) updateIndex(ctx context.Context, added, removed []string) {
+	p.indexMu.Lock()
+	defer p.indexMu.Unlock()
+
+	index := p.loadIndex(ctx)
+	present := make(map[string]bool, len(index))
 	for _, k := range index {
-		if k == key {
-			return // Already in index
-		}
+		present[k] = true
 	}
-	index = append(index, key)
-	p.saveIndex(index)
-}
 
-// removeFromIndex removes a key from the index.
-func (p *Provider) removeFromIndex(key string) {
-	index := p.loadIndex()
-	newIndex := make([]string, 0, len(index))
-	for _, k := range index {
-		if k != key {
-			newIndex = append(newIndex, k)
+	changed := false
+	if len(removed) > 0 {
+		drop := make(map[string]bool, len(removed))
+		for _, k := range removed {
+			if present[k] {
+				drop[k] = true
+				delete(present, k)
+			}
 		}
+		if len(drop) > 0 {
+			newIndex := make([]string, 0, len(index))
+			for _, k := range index {
+				if !drop[k] {
+					newIndex = append(newIndex, k)
+				}
+			}
+			index = newIndex
+			changed = true
+		}
+	}
+	for _, k := range added {
+		if !present[k] {
+			present[k] = true
+			index = append(index, k)
+			changed = true
+		}
+	}
+
+	if changed {
+		p.saveIndex(ctx, index)
 	}
-	p.saveIndex(newIndex)
 }
 
 // Ensure Provider implements vault.Vault.
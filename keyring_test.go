@@ -5,14 +5,15 @@ import (
 	"errors"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/agentplexus/omnivault/vault"
-	zkeyring "github.com/zalando/go-keyring"
 )
 
 func init() {
-	// Use mock keyring for all tests
-	zkeyring.MockInit()
+	// Use an in-memory keyring for all tests
+	shared := newMemoryBackend()
+	newBackend = func(Config) backend { return shared }
 }
 
 func TestNew(t *testing.T) {
@@ -293,6 +294,9 @@ func TestProvider_Capabilities(t *testing.T) {
 		if caps.MultiField {
 			t.Error("expected MultiField to be false without JSONFormat")
 		}
+		if !caps.Batch {
+			t.Error("expected Batch to be true")
+		}
 	})
 
 	t.Run("with JSON format", func(t *testing.T) {
@@ -433,6 +437,77 @@ func TestProvider_ConcurrentAccess(t *testing.T) {
 	_ = p.Delete(ctx, "concurrent-key")
 }
 
+func TestProvider_ContextCancellation(t *testing.T) {
+	b := newMemoryBackend()
+	useBackend(t, b)
+
+	p := New(Config{ServiceName: "test-ctx", DefaultTimeout: 20 * time.Millisecond})
+	defer p.Close()
+
+	_ = p.Set(context.Background(), "key", &vault.Secret{Value: "v"})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if _, err := p.Get(ctx, "key"); !errors.Is(err, context.Canceled) {
+			t.Errorf("Get: expected context.Canceled, got %v", err)
+		}
+		if err := p.Set(ctx, "key", &vault.Secret{Value: "new"}); !errors.Is(err, context.Canceled) {
+			t.Errorf("Set: expected context.Canceled, got %v", err)
+		}
+		if _, err := p.List(ctx, ""); !errors.Is(err, context.Canceled) {
+			t.Errorf("List: expected context.Canceled, got %v", err)
+		}
+	})
+
+	// Simulate an unlock prompt nobody answers
+	release := make(chan struct{})
+	defer close(release)
+	b.failWith(func(op, _, user string) error {
+		if op == "Get" && user == "key" {
+			<-release
+		}
+		return nil
+	})
+
+	t.Run("default timeout", func(t *testing.T) {
+		start := time.Now()
+		_, err := p.Get(context.Background(), "key")
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected context.DeadlineExceeded, got %v", err)
+		}
+		var vaultErr *vault.VaultError
+		if !errors.As(err, &vaultErr) || vaultErr.Op != "Get" {
+			t.Errorf("expected a Get VaultError, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Get took %v despite the timeout", elapsed)
+		}
+	})
+}
+
+func TestProvider_ContextDeadlineOverridesDefault(t *testing.T) {
+	b := newMemoryBackend()
+	useBackend(t, b)
+
+	p := New(Config{ServiceName: "test-ctx-deadline", DefaultTimeout: time.Hour})
+	defer p.Close()
+
+	release := make(chan struct{})
+	defer close(release)
+	b.failWith(func(string, string, string) error {
+		<-release
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.Exists(ctx, "key"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
 func TestProvider_ImplementsVaultInterface(t *testing.T) {
 	var _ vault.Vault = (*Provider)(nil)
 }
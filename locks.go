@@ -0,0 +1,53 @@
+package keyring
+
+import (
+	"hash/fnv"
+	"slices"
+	"sync"
+)
+
+// lockStripes is the number of locks paths are spread over.
+const lockStripes = 64
+
+// pathLocks serializes writes to the same path while letting writes to
+// different paths run concurrently. Paths are hashed onto a fixed set of
+// striped locks, so unrelated paths occasionally share a lock.
+//
+// Locking order across the provider is: p.mu, then path locks in stripe
+// order, then the journal lock, then the index lock. Writes to a single
+// path, or a known set of paths, hold p.mu for reading plus the locks of
+// their paths. Reads hold only p.mu for reading. Operations that work on
+// the index as a whole or must appear atomic to readers, such as Txn,
+// Rename and DeletePrefix, hold p.mu for writing instead, which excludes
+// everything else.
+type pathLocks struct {
+	stripes [lockStripes]sync.Mutex
+}
+
+// stripe returns the index of the lock for path.
+func stripe(path string) int {
+	h := fnv.New32a()
+	h.Write([]byte(path))
+	return int(h.Sum32() % lockStripes)
+}
+
+// lock write-locks paths and returns a function that unlocks them. Paths
+// sharing a stripe are locked once, and stripes are locked in order, so
+// concurrent callers with overlapping paths can't deadlock.
+func (l *pathLocks) lock(paths ...string) (unlock func()) {
+	stripes := make([]int, len(paths))
+	for i, path := range paths {
+		stripes[i] = stripe(path)
+	}
+	slices.Sort(stripes)
+	stripes = slices.Compact(stripes)
+
+	for _, s := range stripes {
+		l.stripes[s].Lock()
+	}
+	return func() {
+		for i := len(stripes) - 1; i >= 0; i-- {
+			l.stripes[stripes[i]].Unlock()
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package keyring
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+// Redacted replaces secret material in log output.
+const Redacted = "[REDACTED]"
+
+// RedactSecret returns a slog.LogValuer that logs secret's path, provider,
+// version and field names, with every value replaced by Redacted.
+//
+//	logger.Info("loaded secret", "secret", keyring.RedactSecret(secret))
+func RedactSecret(secret *vault.Secret) slog.LogValuer {
+	return redactedSecret{secret}
+}
+
+// redactedSecret is the slog.LogValuer returned by RedactSecret.
+type redactedSecret struct{ secret *vault.Secret }
+
+// LogValue implements slog.LogValuer.
+func (r redactedSecret) LogValue() slog.Value {
+	s := r.secret
+	if s == nil {
+		return slog.StringValue("<nil>")
+	}
+	return redactedValue(s.Metadata, s.Value != "" || len(s.ValueBytes) > 0, slices.Sorted(maps.Keys(s.Fields)))
+}
+
+// LogValue implements slog.LogValuer, so that logging a SecureSecret records
+// its metadata and field names but never its values.
+func (s *SecureSecret) LogValue() slog.Value {
+	if s == nil {
+		return slog.StringValue("<nil>")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return redactedValue(s.Metadata, s.value != nil, slices.Sorted(maps.Keys(s.fields)))
+}
+
+// redactedValue returns the log value of a secret with the given metadata,
+// value and field names.
+func redactedValue(meta vault.Metadata, hasValue bool, fields []string) slog.Value {
+	var attrs []slog.Attr
+	if meta.Path != "" {
+		attrs = append(attrs, slog.String("path", meta.Path))
+	}
+	if meta.Provider != "" {
+		attrs = append(attrs, slog.String("provider", meta.Provider))
+	}
+	if meta.Version != "" {
+		attrs = append(attrs, slog.String("version", meta.Version))
+	}
+	if hasValue {
+		attrs = append(attrs, slog.String("value", Redacted))
+	}
+	if len(fields) > 0 {
+		redacted := make([]slog.Attr, len(fields))
+		for i, name := range fields {
+			redacted[i] = slog.String(name, Redacted)
+		}
+		attrs = append(attrs, slog.Attr{Key: "fields", Value: slog.GroupValue(redacted...)})
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// NewRedactingHandler returns a handler that passes records on to h with
+// any vault.Secret, *vault.Secret or *SecureSecret attribute replaced by
+// its redacted form, so secrets logged by mistake never reach the output.
+// Secrets nested inside other values, such as structs or maps, are not
+// found.
+//
+//	logger := slog.New(keyring.NewRedactingHandler(slog.NewJSONHandler(os.Stderr, nil)))
+func NewRedactingHandler(h slog.Handler) slog.Handler {
+	return redactingHandler{h}
+}
+
+// redactingHandler is the handler returned by NewRedactingHandler.
+type redactingHandler struct{ next slog.Handler }
+
+func (h redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h redactingHandler) Handle(ctx context.Context, r slog.Record) error {
+	redacted := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = redactAttr(a)
+	}
+	return redactingHandler{h.next.WithAttrs(redacted)}
+}
+
+func (h redactingHandler) WithGroup(name string) slog.Handler {
+	return redactingHandler{h.next.WithGroup(name)}
+}
+
+// redactAttr returns a with secrets, including those in groups, redacted.
+func redactAttr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		group := v.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			redacted[i] = redactAttr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redacted...)}
+	case slog.KindAny:
+		switch s := v.Any().(type) {
+		case *vault.Secret:
+			return slog.Attr{Key: a.Key, Value: redactedSecret{s}.LogValue()}
+		case vault.Secret:
+			return slog.Attr{Key: a.Key, Value: redactedSecret{&s}.LogValue()}
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+// newLogger returns the provider's logger: Config.Logger behind a
+// redacting handler, or a logger that discards everything.
+func newLogger(config Config) *slog.Logger {
+	if config.Logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return slog.New(NewRedactingHandler(config.Logger.Handler()))
+}
+
+// logPath returns the attribute identifying path in log records. Paths
+// hidden with Config.PathKey are logged as their keyed hash.
+func (p *Provider) logPath(path string) slog.Attr {
+	if p.paths != nil && !isReservedKey(path) {
+		return slog.String("path_hash", p.paths.key(path))
+	}
+	return slog.String("path", path)
+}
@@ -0,0 +1,262 @@
+package keyring
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+// logRecords returns a logger writing JSON records at debug level, and a
+// function returning the records written so far.
+func logRecords(t *testing.T) (*slog.Logger, func() []map[string]any) {
+	t.Helper()
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	return logger, func() []map[string]any {
+		var records []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var record map[string]any
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				t.Fatalf("invalid log line %q: %v", line, err)
+			}
+			records = append(records, record)
+		}
+		return records
+	}
+}
+
+// findRecord returns the first record with the given message.
+func findRecord(records []map[string]any, msg string) map[string]any {
+	for _, r := range records {
+		if r["msg"] == msg {
+			return r
+		}
+	}
+	return nil
+}
+
+func TestRedactSecret(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	secret := &vault.Secret{
+		Value:    "hunter2",
+		Fields:   map[string]string{"password": "s3cret", "user": "admin"},
+		Metadata: vault.Metadata{Path: "db/creds", Provider: "keyring"},
+	}
+	logger.Info("loaded", "secret", RedactSecret(secret), "none", RedactSecret(nil))
+
+	out := buf.String()
+	for _, leak := range []string{"hunter2", "s3cret", "admin"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("log output contains %q: %s", leak, out)
+		}
+	}
+	for _, want := range []string{"secret.path=db/creds", "secret.value=" + Redacted, "secret.fields.password=" + Redacted, "none=<nil>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log output missing %q: %s", want, out)
+		}
+	}
+}
+
+func TestRedactingHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewRedactingHandler(slog.NewJSONHandler(&buf, nil)))
+
+	secret := &vault.Secret{Value: "hunter2", Fields: map[string]string{"token": "tok-123"}}
+	secure := &SecureSecret{value: newLockedBuffer(7)}
+	copy(secure.value.data, "s3cure!")
+	defer secure.Destroy()
+
+	logger.With("bound", secret).Info("oops",
+		"pointer", secret,
+		"value", *secret,
+		"secure", secure,
+		slog.Group("nested", "secret", secret),
+	)
+	logger.WithGroup("g").Info("oops", "secret", secret)
+
+	out := buf.String()
+	for _, leak := range []string{"hunter2", "tok-123", "s3cure!"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("log output contains %q: %s", leak, out)
+		}
+	}
+	if n := strings.Count(out, Redacted); n < 10 {
+		t.Errorf("log output has %d redacted values, want at least 10: %s", n, out)
+	}
+}
+
+func TestLoggingDisabledByDefault(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+	p := New(Config{ServiceName: "logging-test"})
+	if p.log == nil {
+		t.Fatal("provider logger is nil")
+	}
+	if p.log.Enabled(context.Background(), slog.LevelError) {
+		t.Error("logger without Config.Logger is enabled")
+	}
+}
+
+func TestLoggingBackendAndIndex(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+	logger, records := logRecords(t)
+	ctx := context.Background()
+
+	p := New(Config{ServiceName: "logging-test", Logger: logger})
+	defer p.Close()
+	if err := p.Set(ctx, "key", &vault.Secret{Value: "hunter2"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if _, err := p.List(ctx, ""); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	got := records()
+	if r := findRecord(got, "keyring: backend selected"); r == nil || r["service"] != "logging-test" || r["level"] != "DEBUG" {
+		t.Errorf("backend selection record = %v", r)
+	}
+	if findRecord(got, "keyring: no index yet") == nil {
+		t.Error("missing record for first index load")
+	}
+	if r := findRecord(got, "keyring: index saved"); r == nil || r["size"] != float64(1) {
+		t.Errorf("index save record = %v", r)
+	}
+	if r := findRecord(got, "keyring: index loaded"); r == nil || r["size"] != float64(1) {
+		t.Errorf("index load record = %v", r)
+	}
+}
+
+func TestLoggingUnusableConfig(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+	logger, records := logRecords(t)
+
+	config := profileConfig()
+	config.Profile = "qa"
+	config.Logger = logger
+	New(config)
+
+	r := findRecord(records(), "keyring: configuration unusable, every operation will fail")
+	if r == nil || r["level"] != "WARN" || r["profile"] != "qa" {
+		t.Errorf("unusable configuration record = %v", r)
+	}
+}
+
+func TestLoggingRetry(t *testing.T) {
+	b := newMemoryBackend()
+	useBackend(t, b)
+	logger, records := logRecords(t)
+	ctx := context.Background()
+
+	p := New(Config{ServiceName: "logging-test", Logger: logger, Retry: RetryPolicy{InitialBackoff: time.Millisecond}})
+	defer p.Close()
+	_ = p.Set(ctx, "key", &vault.Secret{Value: "v"})
+
+	failures := 1
+	b.failWith(func(op, _, user string) error {
+		if op == "Get" && user == "key" && failures > 0 {
+			failures--
+			return errDaemonStarting
+		}
+		return nil
+	})
+	if _, err := p.Get(ctx, "key"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	r := findRecord(records(), "keyring: retrying backend call")
+	if r == nil || r["op"] != "Get" || r["path"] != "key" || r["attempt"] != float64(1) {
+		t.Errorf("retry record = %v", r)
+	}
+}
+
+func TestLoggingFallback(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+	logger, records := logRecords(t)
+	ctx := context.Background()
+
+	config := profileConfig()
+	config.Profile = "base"
+	if err := New(config).Set(ctx, "shared/license", &vault.Secret{Value: "base-license"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	config.Profile = "staging"
+	config.Logger = logger
+	staging := New(config)
+	if _, err := staging.Get(ctx, "shared/license"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	r := findRecord(records(), "keyring: secret not found, trying fallback profile")
+	if r == nil || r["path"] != "shared/license" || r["profile"] != "staging" || r["fallback"] != "base" {
+		t.Errorf("fallback record = %v", r)
+	}
+}
+
+func TestLoggingFormatFallback(t *testing.T) {
+	b := newMemoryBackend()
+	useBackend(t, b)
+	logger, records := logRecords(t)
+	ctx := context.Background()
+
+	p := New(Config{ServiceName: "logging-test", JSONFormat: true, Logger: logger})
+	defer p.Close()
+	if err := b.Set("logging-test", "plain", "not json"); err != nil {
+		t.Fatalf("backend Set() error = %v", err)
+	}
+	secret, err := p.Get(ctx, "plain")
+	if err != nil || secret.Value != "not json" {
+		t.Fatalf("Get() = %v, %v; want plain value", secret, err)
+	}
+
+	r := findRecord(records(), "keyring: stored value is not JSON, returning it as a plain value")
+	if r == nil || r["path"] != "plain" {
+		t.Errorf("format fallback record = %v", r)
+	}
+	if strings.Contains(strings.Join(recordValues(records()), " "), "not json") {
+		t.Error("log output contains the stored value")
+	}
+}
+
+func TestLoggingObfuscatedPaths(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+	logger, records := logRecords(t)
+	ctx := context.Background()
+
+	p := New(Config{ServiceName: "logging-test", PathKey: NewPathKey(), Logger: logger})
+	defer p.Close()
+	if _, err := p.Get(ctx, "team/db-password"); err == nil {
+		t.Fatal("Get() of missing secret succeeded")
+	}
+	_ = p.Set(ctx, "team/db-password", &vault.Secret{Value: "v"})
+
+	got := records()
+	if len(got) == 0 {
+		t.Fatal("nothing was logged")
+	}
+	if strings.Contains(strings.Join(recordValues(got), " "), "db-password") {
+		t.Errorf("log output contains an obfuscated path: %v", got)
+	}
+}
+
+// recordValues returns the string values of every record.
+func recordValues(records []map[string]any) []string {
+	var values []string
+	for _, r := range records {
+		for _, v := range r {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
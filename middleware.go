@@ -0,0 +1,93 @@
+package keyring
+
+import (
+	"context"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+// Request describes a provider operation passed through Config.Middleware.
+// Its paths have already been canonicalized. Handlers must not modify it.
+//
+// Single-path operations pass through the chain once. GetMany, SetMany,
+// DeleteMany and DeletePrefix pass each path through separately, so every
+// path can succeed or fail on its own. Txn passes through once with the
+// paths it writes; reads made with Tx.Get are part of the transaction.
+type Request struct {
+	// Op is the operation, named as in the errors it returns: "Get",
+	// "SetMany", "Rename" and so on.
+	Op string
+
+	// Path is the secret the operation acts on. It is the prefix for List
+	// and MovePrefix, and the source for Rename and Copy.
+	Path string
+
+	// Target is the destination of Rename, Copy and MovePrefix.
+	Target string
+
+	// Paths lists the secrets a Txn sets or deletes.
+	Paths []string
+
+	// Secret is the secret stored by Set, SetMany, SetIfNotExists and
+	// SetIfMatch.
+	Secret *vault.Secret
+
+	// ETag is the ETag SetIfMatch expects; "" for SetIfNotExists.
+	ETag string
+}
+
+// Response is the result of an operation. Only the field holding the
+// operation's result is set.
+type Response struct {
+	// Secret is the secret read by Get and GetMany.
+	Secret *vault.Secret
+
+	// Secure is the secret read by GetSecure.
+	Secure *SecureSecret
+
+	// Exists is the result of Exists.
+	Exists bool
+
+	// Paths are the paths returned by List and MovePrefix.
+	Paths []string
+}
+
+// Handler performs an operation, or passes it on to the next handler.
+type Handler func(ctx context.Context, req *Request) (*Response, error)
+
+// Middleware wraps a Handler with behavior such as auditing, metrics,
+// access policy or fault injection. It may call next, possibly with a
+// derived context, or return its own response or error without calling
+// it. Errors it returns reach the caller unchanged.
+type Middleware func(next Handler) Handler
+
+// providerKey is the context key of the provider performing an operation.
+type providerKey struct{}
+
+// providerFrom returns the provider performing the operation in ctx. For an
+// operation passed on to a fallback profile, it is the provider it was
+// called on.
+func providerFrom(ctx context.Context) *Provider {
+	p, _ := ctx.Value(providerKey{}).(*Provider)
+	return p
+}
+
+// handle passes req through the middleware chain, with fn performing the
+// operation at its end. The returned response is never nil.
+func (p *Provider) handle(ctx context.Context, req *Request, fn Handler) (*Response, error) {
+	if providerFrom(ctx) == nil {
+		ctx = context.WithValue(ctx, providerKey{}, p)
+	}
+	h := fn
+	if p.telemetry != nil {
+		h = p.telemetry.middleware(h)
+	}
+	for i := len(p.config.Middleware) - 1; i >= 0; i-- {
+		h = p.config.Middleware[i](h)
+	}
+	resp, err := h(ctx, req)
+	if resp == nil {
+		resp = &Response{}
+	}
+	return resp, err
+}
@@ -0,0 +1,246 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+// requestLog is a middleware that records the requests passing through it.
+type requestLog struct {
+	mu   sync.Mutex
+	reqs []string
+}
+
+func (l *requestLog) middleware(name string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) (*Response, error) {
+			l.mu.Lock()
+			l.reqs = append(l.reqs, strings.TrimSpace(name+" "+req.Op+" "+req.Path+" "+req.Target))
+			l.mu.Unlock()
+			return next(ctx, req)
+		}
+	}
+}
+
+func (l *requestLog) sorted() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	reqs := append([]string(nil), l.reqs...)
+	sort.Strings(reqs)
+	return reqs
+}
+
+// deny is a middleware that rejects every operation on path.
+func deny(path string, err error) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) (*Response, error) {
+			if req.Path == path {
+				return nil, err
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+	ctx := context.Background()
+	var log requestLog
+	p := New(Config{ServiceName: "middleware-test", Middleware: []Middleware{
+		log.middleware("outer"),
+		log.middleware("inner"),
+	}})
+
+	if err := p.Set(ctx, "a//b", &vault.Secret{Value: "v"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if secret, err := p.Get(ctx, "a/b"); err != nil || secret.Value != "v" {
+		t.Fatalf("Get() = %v, %v", secret, err)
+	}
+	if ok, err := p.Exists(ctx, "a/b"); err != nil || !ok {
+		t.Fatalf("Exists() = %v, %v", ok, err)
+	}
+	if paths, err := p.List(ctx, "a/"); err != nil || len(paths) != 1 {
+		t.Fatalf("List() = %v, %v", paths, err)
+	}
+	if err := p.Rename(ctx, "a/b", "a/c"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if err := p.Delete(ctx, "a/c"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	want := []string{
+		"outer Set a/b", "inner Set a/b",
+		"outer Get a/b", "inner Get a/b",
+		"outer Exists a/b", "inner Exists a/b",
+		"outer List a/", "inner List a/",
+		"outer Rename a/b a/c", "inner Rename a/b a/c",
+		"outer Delete a/c", "inner Delete a/c",
+	}
+	if !reflect.DeepEqual(log.reqs, want) {
+		t.Errorf("middleware saw %q, want %q", log.reqs, want)
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	b := newMemoryBackend()
+	useBackend(t, b)
+	ctx := context.Background()
+	errDenied := errors.New("denied by policy")
+	p := New(Config{ServiceName: "middleware-test", Middleware: []Middleware{
+		deny("prod/db", errDenied),
+		// Serve a fixed secret without touching the keyring.
+		func(next Handler) Handler {
+			return func(ctx context.Context, req *Request) (*Response, error) {
+				if req.Op == "Get" && req.Path == "static" {
+					return &Response{Secret: &vault.Secret{Value: "fixed"}}, nil
+				}
+				return next(ctx, req)
+			}
+		},
+	}})
+
+	if err := p.Set(ctx, "prod/db", &vault.Secret{Value: "v"}); err != errDenied {
+		t.Errorf("Set() error = %v, want the middleware's error", err)
+	}
+	if _, err := p.Get(ctx, "prod//db"); err != errDenied {
+		t.Errorf("Get() of a non-canonical path error = %v, want the middleware's error", err)
+	}
+	if b.count("Set", "prod/db") != 0 || b.count("Get", "prod/db") != 0 {
+		t.Error("denied operations reached the backend")
+	}
+	if secret, err := p.Get(ctx, "static"); err != nil || secret.Value != "fixed" {
+		t.Errorf("Get() = %v, %v; want the middleware's secret", secret, err)
+	}
+	if n := b.count("Get", "static"); n != 0 {
+		t.Errorf("backend Get called %d times, want 0", n)
+	}
+}
+
+func TestMiddlewarePerPathInBatches(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+	ctx := context.Background()
+	errDenied := errors.New("denied by policy")
+	var log requestLog
+	p := New(Config{ServiceName: "middleware-test", Middleware: []Middleware{
+		log.middleware(""),
+		deny("b", errDenied),
+	}})
+
+	results, err := p.SetMany(ctx, map[string]*vault.Secret{
+		"a": {Value: "1"},
+		"b": {Value: "2"},
+		"c": {Value: "3"},
+	})
+	if !errors.Is(err, errDenied) {
+		t.Fatalf("SetMany() error = %v, want errDenied", err)
+	}
+	for _, r := range results {
+		if (r.Err != nil) != (r.Path == "b") {
+			t.Errorf("SetMany() result for %s: %v", r.Path, r.Err)
+		}
+	}
+	if paths, _ := p.List(ctx, ""); !reflect.DeepEqual(paths, []string{"a", "c"}) {
+		t.Errorf("List() = %v, want [a c]", paths)
+	}
+
+	if _, err := p.GetMany(ctx, []string{"a", "c"}); err != nil {
+		t.Fatalf("GetMany() error = %v", err)
+	}
+	if _, err := p.DeleteMany(ctx, []string{"a", "c"}); err != nil {
+		t.Fatalf("DeleteMany() error = %v", err)
+	}
+
+	want := []string{
+		"DeleteMany a", "DeleteMany c",
+		"GetMany a", "GetMany c",
+		"List",
+		"SetMany a", "SetMany b", "SetMany c",
+	}
+	if got := log.sorted(); !reflect.DeepEqual(got, want) {
+		t.Errorf("middleware saw %q, want %q", got, want)
+	}
+}
+
+func TestMiddlewareTxn(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+	ctx := context.Background()
+	var paths []string
+	errFault := errors.New("injected fault")
+	fail := false
+	p := New(Config{ServiceName: "middleware-test", Middleware: []Middleware{
+		func(next Handler) Handler {
+			return func(ctx context.Context, req *Request) (*Response, error) {
+				if req.Op == "Txn" {
+					paths = req.Paths
+					if fail {
+						return nil, errFault
+					}
+				}
+				return next(ctx, req)
+			}
+		},
+	}})
+
+	err := p.Txn(ctx, func(tx *Tx) error {
+		tx.Set("x", &vault.Secret{Value: "1"})
+		return tx.Delete("y")
+	})
+	if err != nil {
+		t.Fatalf("Txn() error = %v", err)
+	}
+	if !reflect.DeepEqual(paths, []string{"x", "y"}) {
+		t.Errorf("Txn request paths = %v, want [x y]", paths)
+	}
+
+	fail = true
+	err = p.Txn(ctx, func(tx *Tx) error {
+		return tx.Set("z", &vault.Secret{Value: "1"})
+	})
+	if err != errFault {
+		t.Errorf("Txn() error = %v, want the injected fault", err)
+	}
+	if ok, _ := p.Exists(ctx, "z"); ok {
+		t.Error("failed Txn was applied")
+	}
+}
+
+func TestMiddlewareFallbackProfile(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+	ctx := context.Background()
+	var log requestLog
+	config := Config{
+		ServiceName: "middleware-test",
+		Profiles: map[string]Profile{
+			"dev":  {Prefix: "dev", Fallback: "base"},
+			"base": {Prefix: "base"},
+		},
+		Profile:    "base",
+		Middleware: []Middleware{log.middleware("")},
+	}
+	if err := New(config).Set(ctx, "shared", &vault.Secret{Value: "v"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	config.Profile = "dev"
+	dev := New(config)
+	if _, err := dev.Get(ctx, "shared"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if ok, err := dev.Exists(ctx, "shared"); err != nil || !ok {
+		t.Fatalf("Exists() = %v, %v", ok, err)
+	}
+
+	want := []string{"Exists shared", "Get shared", "Set shared"}
+	if got := log.sorted(); !reflect.DeepEqual(got, want) {
+		t.Errorf("middleware saw %q, want %q; fallback lookups are part of the operation", got, want)
+	}
+}
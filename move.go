@@ -0,0 +1,181 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/agentplexus/omnivault/vault"
+	zkeyring "github.com/zalando/go-keyring"
+)
+
+// Rename moves the secret at from to the path to.
+//
+// The stored value is moved verbatim, so versions, tags and other metadata
+// are preserved. The move is applied atomically with the same journaling as
+// Txn: callers never observe the secret at both paths or at neither.
+// It returns vault.ErrSecretNotFound if from doesn't exist and
+// vault.ErrAlreadyExists if to is already in use.
+func (p *Provider) Rename(ctx context.Context, from, to string) error {
+	return p.relocate(ctx, "Rename", from, to, true)
+}
+
+// Copy duplicates the secret at from to the path to, preserving its value
+// and metadata. It returns vault.ErrSecretNotFound if from doesn't exist
+// and vault.ErrAlreadyExists if to is already in use.
+func (p *Provider) Copy(ctx context.Context, from, to string) error {
+	return p.relocate(ctx, "Copy", from, to, false)
+}
+
+// MovePrefix renames every indexed secret under oldPrefix to the same
+// relative path under newPrefix, and returns the new paths.
+//
+// All secrets are moved in a single atomic commit with one index update.
+// Nothing is moved if any destination is already in use, in which case
+// vault.ErrAlreadyExists is returned. The prefixes must not overlap.
+func (p *Provider) MovePrefix(ctx context.Context, oldPrefix, newPrefix string) ([]string, error) {
+	if err := p.ensureRecovered(ctx); err != nil {
+		return nil, vault.NewVaultError("MovePrefix", oldPrefix, p.Name(), err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, vault.NewVaultError("MovePrefix", oldPrefix, p.Name(), vault.ErrClosed)
+	}
+	oldPrefix, err := p.canonicalPrefix("MovePrefix", oldPrefix)
+	if err != nil {
+		return nil, err
+	}
+	newPrefix, err = p.canonicalPrefix("MovePrefix", newPrefix)
+	if err != nil {
+		return nil, err
+	}
+	if oldPrefix == "" || strings.HasPrefix(newPrefix, oldPrefix) || strings.HasPrefix(oldPrefix, newPrefix) {
+		return nil, vault.NewVaultError("MovePrefix", oldPrefix, p.Name(),
+			fmt.Errorf("%w: prefixes %q and %q overlap", vault.ErrInvalidPath, oldPrefix, newPrefix))
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := p.handle(ctx, &Request{Op: "MovePrefix", Path: oldPrefix, Target: newPrefix}, func(ctx context.Context, req *Request) (*Response, error) {
+		moved, err := p.movePrefix(ctx, oldPrefix, newPrefix)
+		return &Response{Paths: moved}, err
+	})
+	return resp.Paths, err
+}
+
+// movePrefix moves the secrets under oldPrefix to newPrefix in one commit.
+// The caller must hold p.mu for writing.
+func (p *Provider) movePrefix(ctx context.Context, oldPrefix, newPrefix string) ([]string, error) {
+	var sources []string
+	for _, key := range p.loadIndex(ctx) {
+		if strings.HasPrefix(key, oldPrefix) {
+			sources = append(sources, key)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, vault.NewVaultError("MovePrefix", oldPrefix, p.Name(), err)
+	}
+
+	var sets, deletes []journalEntry
+	var moved []string
+	for _, from := range sources {
+		if err := ctx.Err(); err != nil {
+			return nil, vault.NewVaultError("MovePrefix", from, p.Name(), err)
+		}
+		value, err := p.read(ctx, from)
+		if err != nil {
+			if errors.Is(err, zkeyring.ErrNotFound) {
+				continue // Stale index entry
+			}
+			return nil, vault.NewVaultError("MovePrefix", from, p.Name(), err)
+		}
+
+		to := newPrefix + strings.TrimPrefix(from, oldPrefix)
+		if err := p.ensureAbsent(ctx, "MovePrefix", to); err != nil {
+			return nil, err
+		}
+
+		sets = append(sets, journalEntry{Path: to, Next: &value})
+		deletes = append(deletes, journalEntry{Path: from})
+		moved = append(moved, to)
+	}
+
+	// Write every destination before removing any source.
+	if err := p.commit(ctx, "MovePrefix", append(sets, deletes...)); err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
+
+// relocate copies the stored value at from to to, removing from if move is
+// set. It holds p.mu for writing so that readers, which take no path locks,
+// never see a half-applied move.
+func (p *Provider) relocate(ctx context.Context, op, from, to string, move bool) error {
+	if err := p.ensureRecovered(ctx); err != nil {
+		return vault.NewVaultError(op, from, p.Name(), err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return vault.NewVaultError(op, from, p.Name(), vault.ErrClosed)
+	}
+	from, err := p.canonical(op, from)
+	if err != nil {
+		return err
+	}
+	to, err = p.canonical(op, to)
+	if err != nil {
+		return err
+	}
+	if from == to {
+		return vault.NewVaultError(op, from, p.Name(), vault.ErrInvalidPath)
+	}
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	_, err = p.handle(ctx, &Request{Op: op, Path: from, Target: to}, func(ctx context.Context, req *Request) (*Response, error) {
+		return nil, p.copyValue(ctx, op, from, to, move)
+	})
+	return err
+}
+
+// copyValue copies the stored value at from to to, removing from if move is
+// set. The caller must hold p.mu for writing.
+func (p *Provider) copyValue(ctx context.Context, op, from, to string, move bool) error {
+	value, err := p.read(ctx, from)
+	if err != nil {
+		if errors.Is(err, zkeyring.ErrNotFound) {
+			return vault.NewVaultError(op, from, p.Name(), vault.ErrSecretNotFound)
+		}
+		return vault.NewVaultError(op, from, p.Name(), err)
+	}
+	if err := p.ensureAbsent(ctx, op, to); err != nil {
+		return err
+	}
+
+	entries := []journalEntry{{Path: to, Next: &value}}
+	if move {
+		entries = append(entries, journalEntry{Path: from})
+	}
+	return p.commit(ctx, op, entries)
+}
+
+// ensureAbsent returns vault.ErrAlreadyExists if a secret is stored at path.
+func (p *Provider) ensureAbsent(ctx context.Context, op, path string) error {
+	_, err := p.read(ctx, path)
+	switch {
+	case err == nil:
+		return vault.NewVaultError(op, path, p.Name(), vault.ErrAlreadyExists)
+	case errors.Is(err, zkeyring.ErrNotFound):
+		return nil
+	default:
+		return vault.NewVaultError(op, path, p.Name(), err)
+	}
+}
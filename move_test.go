@@ -0,0 +1,151 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+func TestProvider_Rename(t *testing.T) {
+	ctx := context.Background()
+	useBackend(t, newMemoryBackend())
+
+	p := New(Config{ServiceName: "test-rename", JSONFormat: true})
+	defer p.Close()
+
+	err := p.Set(ctx, "db/pass", &vault.Secret{
+		Value:    "secret",
+		Metadata: vault.Metadata{Version: "3", Tags: map[string]string{"env": "prod"}},
+	})
+	if err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	if err := p.Rename(ctx, "db/pass", "database/prod/password"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+
+	if exists, _ := p.Exists(ctx, "db/pass"); exists {
+		t.Error("expected source to be removed")
+	}
+	secret, err := p.Get(ctx, "database/prod/password")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if secret.Value != "secret" || secret.Metadata.Version != "3" || secret.Metadata.Tags["env"] != "prod" {
+		t.Errorf("expected value and metadata to be preserved, got %+v", secret)
+	}
+	if secret.Metadata.Path != "database/prod/password" {
+		t.Errorf("expected path to reflect new location, got %q", secret.Metadata.Path)
+	}
+
+	list, _ := p.List(ctx, "")
+	if len(list) != 1 || list[0] != "database/prod/password" {
+		t.Errorf("expected index to contain only the new path, got %v", list)
+	}
+
+	t.Run("missing source", func(t *testing.T) {
+		err := p.Rename(ctx, "nope", "elsewhere")
+		if !errors.Is(err, vault.ErrSecretNotFound) {
+			t.Errorf("expected ErrSecretNotFound, got %v", err)
+		}
+	})
+
+	t.Run("existing destination", func(t *testing.T) {
+		_ = p.Set(ctx, "other", &vault.Secret{Value: "x"})
+		err := p.Rename(ctx, "other", "database/prod/password")
+		if !errors.Is(err, vault.ErrAlreadyExists) {
+			t.Errorf("expected ErrAlreadyExists, got %v", err)
+		}
+		if exists, _ := p.Exists(ctx, "other"); !exists {
+			t.Error("expected source to remain after failed rename")
+		}
+	})
+}
+
+func TestProvider_Copy(t *testing.T) {
+	ctx := context.Background()
+	useBackend(t, newMemoryBackend())
+
+	p := New(Config{ServiceName: "test-copy"})
+	defer p.Close()
+
+	if err := p.Set(ctx, "src", &vault.Secret{Value: "v"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := p.Copy(ctx, "src", "dst"); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	for _, path := range []string{"src", "dst"} {
+		secret, err := p.Get(ctx, path)
+		if err != nil || secret.Value != "v" {
+			t.Errorf("expected %s=v, got %v, %v", path, secret, err)
+		}
+	}
+	list, _ := p.List(ctx, "")
+	if len(list) != 2 {
+		t.Errorf("expected both paths indexed, got %v", list)
+	}
+}
+
+func TestProvider_MovePrefix(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBackend()
+	useBackend(t, b)
+
+	p := New(Config{ServiceName: "test-move-prefix"})
+	defer p.Close()
+
+	_, err := p.SetMany(ctx, map[string]*vault.Secret{
+		"old/a":   {Value: "1"},
+		"old/b/c": {Value: "2"},
+		"keep":    {Value: "3"},
+	})
+	if err != nil {
+		t.Fatalf("SetMany failed: %v", err)
+	}
+	indexWrites := b.count("Set", indexKey)
+
+	moved, err := p.MovePrefix(ctx, "old/", "new/")
+	if err != nil {
+		t.Fatalf("MovePrefix failed: %v", err)
+	}
+	sort.Strings(moved)
+	if len(moved) != 2 || moved[0] != "new/a" || moved[1] != "new/b/c" {
+		t.Errorf("unexpected moved paths %v", moved)
+	}
+	if n := b.count("Set", indexKey) - indexWrites; n != 1 {
+		t.Errorf("expected a single index write, got %d", n)
+	}
+
+	secret, err := p.Get(ctx, "new/b/c")
+	if err != nil || secret.Value != "2" {
+		t.Errorf("expected new/b/c=2, got %v, %v", secret, err)
+	}
+	if list, _ := p.List(ctx, "old/"); len(list) != 0 {
+		t.Errorf("expected nothing left under old/, got %v", list)
+	}
+
+	t.Run("conflict moves nothing", func(t *testing.T) {
+		_ = p.Set(ctx, "other/a", &vault.Secret{Value: "x"})
+		_ = p.Set(ctx, "new2/a", &vault.Secret{Value: "taken"})
+		_, err := p.MovePrefix(ctx, "other/", "new2/")
+		if !errors.Is(err, vault.ErrAlreadyExists) {
+			t.Errorf("expected ErrAlreadyExists, got %v", err)
+		}
+		if exists, _ := p.Exists(ctx, "other/a"); !exists {
+			t.Error("expected source to remain after conflict")
+		}
+	})
+
+	t.Run("overlapping prefixes", func(t *testing.T) {
+		_, err := p.MovePrefix(ctx, "new/", "new/sub/")
+		if !errors.Is(err, vault.ErrInvalidPath) {
+			t.Errorf("expected ErrInvalidPath, got %v", err)
+		}
+	})
+}
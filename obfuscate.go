@@ -0,0 +1,113 @@
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MinPathKeySize is the minimum length in bytes of Config.PathKey.
+const MinPathKeySize = 32
+
+// sealedPrefix marks an index or journal value encrypted by a pathCipher.
+const sealedPrefix = "sealed:v1:"
+
+// ErrSealed is reported when the index or journal can't be decrypted, most
+// likely because Config.PathKey differs from the key it was written with.
+var ErrSealed = errors.New("cannot decrypt sealed record")
+
+// NewPathKey returns a random key suitable for Config.PathKey. Store it
+// somewhere safe: without it, obfuscated secrets can't be found again.
+func NewPathKey() []byte {
+	key := make([]byte, MinPathKeySize)
+	_, _ = rand.Read(key)
+	return key
+}
+
+// pathCipher hides paths from the backend. It maps each path to an opaque
+// key and encrypts the records, such as the index, that list paths.
+type pathCipher struct {
+	names []byte      // HMAC key for backend keys
+	aead  cipher.AEAD // encrypts records
+}
+
+// newPathCipher derives independent name and record keys from key.
+func newPathCipher(key []byte) (*pathCipher, error) {
+	if len(key) < MinPathKeySize {
+		return nil, fmt.Errorf("path key is %d bytes, need at least %d", len(key), MinPathKeySize)
+	}
+	block, err := aes.NewCipher(deriveKey(key, "records"))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &pathCipher{names: deriveKey(key, "names"), aead: aead}, nil
+}
+
+// deriveKey derives a 256-bit subkey of key for purpose.
+func deriveKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("omnivault-keyring " + purpose))
+	return mac.Sum(nil)
+}
+
+// key returns the backend key for path.
+func (c *pathCipher) key(path string) string {
+	mac := hmac.New(sha256.New, c.names)
+	mac.Write([]byte(path))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// seal encrypts a record stored under name. The name is authenticated, so
+// a sealed index can't be passed off as the journal or vice versa.
+func (c *pathCipher) seal(name string, plaintext []byte) string {
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plaintext)+c.aead.Overhead())
+	_, _ = rand.Read(nonce)
+	sealed := c.aead.Seal(nonce, nonce, plaintext, []byte(name))
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed)
+}
+
+// open decrypts a record sealed under name.
+func (c *pathCipher) open(name, value string) ([]byte, error) {
+	encoded, ok := strings.CutPrefix(value, sealedPrefix)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not sealed", ErrSealed, name)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return nil, fmt.Errorf("%w: %s is malformed", ErrSealed, name)
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrSealed, name, err)
+	}
+	return plaintext, nil
+}
+
+// sealRecord returns the value to store for a bookkeeping record, encrypted
+// if paths are obfuscated.
+func (p *Provider) sealRecord(name string, data []byte) string {
+	if p.paths == nil {
+		return string(data)
+	}
+	return p.paths.seal(name, data)
+}
+
+// openRecord returns the contents of a bookkeeping record read from the
+// keyring, decrypting it if paths are obfuscated.
+func (p *Provider) openRecord(name, value string) ([]byte, error) {
+	if p.paths == nil {
+		return []byte(value), nil
+	}
+	return p.paths.open(name, value)
+}
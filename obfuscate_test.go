@@ -0,0 +1,118 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+func TestObfuscatedPaths(t *testing.T) {
+	b := newMemoryBackend()
+	useBackend(t, b)
+	ctx := context.Background()
+
+	key := NewPathKey()
+	p := New(Config{ServiceName: "obfuscate-test", PathKey: key})
+
+	if err := p.Set(ctx, "customers/acme/stripe-live-key", &vault.Secret{Value: "sk_live"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := p.Set(ctx, "customers/globex/stripe-live-key", &vault.Secret{Value: "sk_globex"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	// Nothing stored in the backend mentions a path.
+	for user, value := range b.store["obfuscate-test"] {
+		for _, leak := range []string{"customers", "acme", "stripe", "__omnivault"} {
+			if strings.Contains(user, leak) || strings.Contains(value, leak) {
+				t.Errorf("backend entry %q = %q leaks %q", user, value, leak)
+			}
+		}
+	}
+
+	secret, err := p.Get(ctx, "customers/acme/stripe-live-key")
+	if err != nil || secret.Value != "sk_live" || secret.Metadata.Path != "customers/acme/stripe-live-key" {
+		t.Errorf("Get() = %+v, %v", secret, err)
+	}
+	paths, err := p.List(ctx, "customers/")
+	if err != nil || !reflect.DeepEqual(paths, []string{"customers/acme/stripe-live-key", "customers/globex/stripe-live-key"}) {
+		t.Errorf("List() = %v, %v", paths, err)
+	}
+
+	if err := p.Rename(ctx, "customers/acme/stripe-live-key", "customers/acme/stripe-key"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if secret, err := p.Get(ctx, "customers/acme/stripe-key"); err != nil || secret.Value != "sk_live" {
+		t.Errorf("Get() after Rename = %v, %v", secret, err)
+	}
+	paths, _ = p.List(ctx, "customers/acme/")
+	if !reflect.DeepEqual(paths, []string{"customers/acme/stripe-key"}) {
+		t.Errorf("List() after Rename = %v", paths)
+	}
+
+	// A new provider with the same key sees the same secrets.
+	again := New(Config{ServiceName: "obfuscate-test", PathKey: key})
+	if secret, err := again.Get(ctx, "customers/globex/stripe-live-key"); err != nil || secret.Value != "sk_globex" {
+		t.Errorf("Get() with same key = %v, %v", secret, err)
+	}
+}
+
+func TestObfuscatedPathsWrongKey(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+	ctx := context.Background()
+
+	p := New(Config{ServiceName: "obfuscate-wrong-key", PathKey: NewPathKey()})
+	if err := p.Set(ctx, "db/password", &vault.Secret{Value: "pw"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	var indexErrs []error
+	other := New(Config{
+		ServiceName:  "obfuscate-wrong-key",
+		PathKey:      NewPathKey(),
+		OnIndexError: func(op string, err error) { indexErrs = append(indexErrs, err) },
+	})
+	if _, err := other.Get(ctx, "db/password"); !errors.Is(err, vault.ErrSecretNotFound) {
+		t.Errorf("Get() with another key error = %v, want ErrSecretNotFound", err)
+	}
+	if paths, _ := other.List(ctx, ""); len(paths) != 0 {
+		t.Errorf("List() with another key = %v, want empty", paths)
+	}
+	if len(indexErrs) != 0 {
+		t.Errorf("index errors = %v; another key finds no index at all", indexErrs)
+	}
+}
+
+func TestObfuscatedPathsShortKey(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+
+	p := New(Config{ServiceName: "obfuscate-short-key", PathKey: []byte("too short")})
+	if err := p.Set(context.Background(), "x", &vault.Secret{Value: "v"}); err == nil {
+		t.Error("Set() with a short path key succeeded")
+	}
+}
+
+func TestPathCipherSeal(t *testing.T) {
+	c, err := newPathCipher(NewPathKey())
+	if err != nil {
+		t.Fatalf("newPathCipher() error = %v", err)
+	}
+
+	sealed := c.seal(indexKey, []byte(`["a"]`))
+	if got, err := c.open(indexKey, sealed); err != nil || string(got) != `["a"]` {
+		t.Errorf("open() = %q, %v", got, err)
+	}
+	if _, err := c.open(journalKey, sealed); !errors.Is(err, ErrSealed) {
+		t.Errorf("open() under another name error = %v, want ErrSealed", err)
+	}
+	if _, err := c.open(indexKey, `["a"]`); !errors.Is(err, ErrSealed) {
+		t.Errorf("open() of a plain record error = %v, want ErrSealed", err)
+	}
+	if c.key("a") == c.key("b") || c.key("a") != c.key("a") {
+		t.Error("key() is not a deterministic function of the path")
+	}
+}
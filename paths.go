@@ -0,0 +1,254 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/agentplexus/omnivault/vault"
+	zkeyring "github.com/zalando/go-keyring"
+	"golang.org/x/text/unicode/norm"
+)
+
+// PathRules controls how paths are validated and canonicalized before they
+// reach the keyring. The zero value applies every rule, so "a//b/" and
+// "a/b" name the same secret and so do the NFC and NFD forms of "café".
+type PathRules struct {
+	// PreserveSlashes keeps leading, trailing and repeated slashes instead
+	// of collapsing "/a//b/" to "a/b".
+	PreserveSlashes bool
+
+	// AllowDotSegments accepts "." and ".." path segments. They are stored
+	// literally, never resolved.
+	AllowDotSegments bool
+
+	// AllowControlChars accepts control characters such as NUL and newline.
+	AllowControlChars bool
+
+	// SkipNormalization keeps paths in the Unicode form they were given in
+	// instead of normalizing them to NFC, and accepts invalid UTF-8.
+	SkipNormalization bool
+
+	// MaxLength is the maximum length of a canonical path in bytes.
+	// Zero means no limit.
+	MaxLength int
+
+	// Validate, if set, is called with every canonical path, but not with
+	// List and Watch prefixes, and rejects it by returning an error, which
+	// is wrapped in an InvalidPathError.
+	Validate func(path string) error
+}
+
+// InvalidPathError is returned for a path rejected by the provider's
+// PathRules. It matches vault.ErrInvalidPath.
+type InvalidPathError struct {
+	// Path is the path as given by the caller.
+	Path string
+
+	// Reason describes the rule the path broke.
+	Reason string
+
+	// Err is the error returned by PathRules.Validate, if it rejected the path.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *InvalidPathError) Error() string {
+	return fmt.Sprintf("%v %q: %s", vault.ErrInvalidPath, e.Path, e.Reason)
+}
+
+// Is reports whether the error matches the target.
+func (e *InvalidPathError) Is(target error) bool {
+	return target == vault.ErrInvalidPath
+}
+
+// Unwrap returns the error returned by PathRules.Validate, if any.
+func (e *InvalidPathError) Unwrap() error {
+	return e.Err
+}
+
+// Canonicalize returns the canonical form of path under the rules, or an
+// *InvalidPathError if the path is rejected. Empty paths and the keys the
+// provider reserves for its own bookkeeping are always rejected.
+func (r PathRules) Canonicalize(path string) (string, error) {
+	canonical, err := r.canonicalize(path, false)
+	if err != nil {
+		return "", err
+	}
+	if isReservedKey(canonical) {
+		return "", &InvalidPathError{Path: path, Reason: "reserved for internal use"}
+	}
+	return canonical, nil
+}
+
+// canonicalPrefix returns the canonical form of a List or Watch prefix.
+// Unlike a path, a prefix may be empty and keeps its trailing slash.
+func (r PathRules) canonicalPrefix(prefix string) (string, error) {
+	return r.canonicalize(prefix, true)
+}
+
+// canonicalize applies the rules to path.
+func (r PathRules) canonicalize(path string, prefix bool) (string, error) {
+	invalid := func(format string, args ...any) error {
+		return &InvalidPathError{Path: path, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	canonical := path
+	if !r.SkipNormalization {
+		if !utf8.ValidString(canonical) {
+			return "", invalid("not valid UTF-8")
+		}
+		canonical = norm.NFC.String(canonical)
+	}
+	if !r.AllowControlChars {
+		if i := strings.IndexFunc(canonical, unicode.IsControl); i >= 0 {
+			c, _ := utf8.DecodeRuneInString(canonical[i:])
+			return "", invalid("control character %U at byte %d", c, i)
+		}
+	}
+
+	if !r.PreserveSlashes {
+		segments := strings.FieldsFunc(canonical, func(c rune) bool { return c == '/' })
+		trailing := prefix && len(segments) > 0 && strings.HasSuffix(canonical, "/")
+		canonical = strings.Join(segments, "/")
+		if trailing {
+			canonical += "/"
+		}
+	}
+	if !r.AllowDotSegments {
+		for _, segment := range strings.Split(canonical, "/") {
+			if segment == "." || segment == ".." {
+				return "", invalid("%q segment", segment)
+			}
+		}
+	}
+
+	if canonical == "" && !prefix {
+		return "", invalid("empty path")
+	}
+	if r.MaxLength > 0 && len(canonical) > r.MaxLength {
+		return "", invalid("%d bytes long, limit is %d", len(canonical), r.MaxLength)
+	}
+	if r.Validate != nil && !prefix {
+		if err := r.Validate(canonical); err != nil {
+			return "", &InvalidPathError{Path: path, Reason: err.Error(), Err: err}
+		}
+	}
+	return canonical, nil
+}
+
+// canonical returns the canonical form of path, or a VaultError for op if
+// Config.PathRules reject it.
+func (p *Provider) canonical(op, path string) (string, error) {
+	canonical, err := p.config.PathRules.Canonicalize(path)
+	if err != nil {
+		return "", vault.NewVaultError(op, path, p.Name(), err)
+	}
+	return canonical, nil
+}
+
+// canonicalPrefix returns the canonical form of prefix, or a VaultError for
+// op if Config.PathRules reject it.
+func (p *Provider) canonicalPrefix(op, prefix string) (string, error) {
+	canonical, err := p.config.PathRules.canonicalPrefix(prefix)
+	if err != nil {
+		return "", vault.NewVaultError(op, prefix, p.Name(), err)
+	}
+	return canonical, nil
+}
+
+// MigratePaths moves every indexed secret whose path isn't canonical under
+// Config.PathRules, typically one stored before paths were canonicalized,
+// to its canonical path, and returns the new paths.
+//
+// Until they are migrated, such secrets are listed by List but can't be
+// reached by Get or Delete, which look up the canonical path instead.
+// The moves are applied in a single atomic commit with one index update.
+// Secrets whose canonical path is already in use, or which the rules
+// reject altogether, are left in place and reported in the returned error,
+// which matches vault.ErrAlreadyExists or vault.ErrInvalidPath; the other
+// secrets are still moved.
+func (p *Provider) MigratePaths(ctx context.Context) ([]string, error) {
+	if err := p.ensureRecovered(ctx); err != nil {
+		return nil, vault.NewVaultError("MigratePaths", "", p.Name(), err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, vault.NewVaultError("MigratePaths", "", p.Name(), vault.ErrClosed)
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := p.handle(ctx, &Request{Op: "MigratePaths"}, func(ctx context.Context, req *Request) (*Response, error) {
+		moved, err := p.migratePaths(ctx)
+		return &Response{Paths: moved}, err
+	})
+	return resp.Paths, err
+}
+
+// migratePaths moves the indexed secrets stored under non-canonical paths
+// in one commit. The caller must hold p.mu for writing.
+func (p *Provider) migratePaths(ctx context.Context) ([]string, error) {
+	index := p.loadIndex(ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, vault.NewVaultError("MigratePaths", "", p.Name(), err)
+	}
+	indexed := make(map[string]bool, len(index))
+	for _, key := range index {
+		indexed[key] = true
+	}
+
+	var sets, deletes []journalEntry
+	var moved []string
+	var errs []error
+	for _, from := range index {
+		to, err := p.config.PathRules.Canonicalize(from)
+		if err != nil {
+			errs = append(errs, vault.NewVaultError("MigratePaths", from, p.Name(), err))
+			continue
+		}
+		if to == from {
+			continue
+		}
+		value, err := p.read(ctx, from)
+		if err != nil {
+			if errors.Is(err, zkeyring.ErrNotFound) {
+				continue // Stale index entry
+			}
+			return nil, vault.NewVaultError("MigratePaths", from, p.Name(), err)
+		}
+
+		// Two spellings of one path, or a secret already at the canonical
+		// path, can't be merged.
+		if indexed[to] {
+			errs = append(errs, vault.NewVaultError("MigratePaths", from, p.Name(),
+				fmt.Errorf("%w: canonical path %q", vault.ErrAlreadyExists, to)))
+			continue
+		}
+		if err := p.ensureAbsent(ctx, "MigratePaths", to); err != nil {
+			if !errors.Is(err, vault.ErrAlreadyExists) {
+				return nil, err
+			}
+			errs = append(errs, err)
+			continue
+		}
+		indexed[to] = true
+
+		sets = append(sets, journalEntry{Path: to, Next: &value})
+		deletes = append(deletes, journalEntry{Path: from})
+		moved = append(moved, to)
+	}
+
+	// Write every destination before removing any source.
+	if err := p.commit(ctx, "MigratePaths", append(sets, deletes...)); err != nil {
+		return nil, err
+	}
+	return moved, errors.Join(errs...)
+}
@@ -0,0 +1,258 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+func TestCanonicalize(t *testing.T) {
+	errNoUpper := errors.New("uppercase not allowed")
+
+	tests := []struct {
+		name  string
+		rules PathRules
+		path  string
+		want  string // "" means the path is rejected
+	}{
+		{"plain", PathRules{}, "db/password", "db/password"},
+		{"double slash", PathRules{}, "db//password", "db/password"},
+		{"leading and trailing slashes", PathRules{}, "/db/password/", "db/password"},
+		{"NFD to NFC", PathRules{}, "cafe\u0301", "caf\u00e9"},
+		{"empty", PathRules{}, "", ""},
+		{"only slashes", PathRules{}, "//", ""},
+		{"dot dot", PathRules{}, "db/../etc", ""},
+		{"dot", PathRules{}, "./db", ""},
+		{"newline", PathRules{}, "db\npassword", ""},
+		{"NUL", PathRules{}, "db\x00", ""},
+		{"invalid UTF-8", PathRules{}, "db\xff", ""},
+		{"reserved", PathRules{}, indexKey, ""},
+		{"preserve slashes", PathRules{PreserveSlashes: true}, "db//password/", "db//password/"},
+		{"allow dot segments", PathRules{AllowDotSegments: true}, "db/../etc", "db/../etc"},
+		{"allow control chars", PathRules{AllowControlChars: true}, "db\tx", "db\tx"},
+		{"skip normalization", PathRules{SkipNormalization: true}, "cafe\u0301", "cafe\u0301"},
+		{"within max length", PathRules{MaxLength: 5}, "a//bcd", "a/bcd"},
+		{"over max length", PathRules{MaxLength: 5}, "abcdef", ""},
+		{"custom rule", PathRules{Validate: func(path string) error {
+			if strings.ToLower(path) != path {
+				return errNoUpper
+			}
+			return nil
+		}}, "DB", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rules.Canonicalize(tt.path)
+			if tt.want == "" {
+				var pathErr *InvalidPathError
+				if !errors.As(err, &pathErr) || !errors.Is(err, vault.ErrInvalidPath) {
+					t.Fatalf("Canonicalize(%q) = %q, %v; want *InvalidPathError", tt.path, got, err)
+				}
+				if pathErr.Path != tt.path {
+					t.Errorf("InvalidPathError.Path = %q, want %q", pathErr.Path, tt.path)
+				}
+				if tt.rules.Validate != nil && !errors.Is(err, errNoUpper) {
+					t.Errorf("error = %v, want it to wrap the Validate error", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Canonicalize(%q) = %q, %v; want %q", tt.path, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalPrefix(t *testing.T) {
+	tests := map[string]string{
+		"":         "",
+		"/":        "",
+		"db":       "db",
+		"db/":      "db/",
+		"db//":     "db/",
+		"//db//x/": "db/x/",
+	}
+	for prefix, want := range tests {
+		if got, err := (PathRules{}).canonicalPrefix(prefix); err != nil || got != want {
+			t.Errorf("canonicalPrefix(%q) = %q, %v; want %q", prefix, got, err, want)
+		}
+	}
+	if _, err := (PathRules{}).canonicalPrefix("../"); !errors.Is(err, vault.ErrInvalidPath) {
+		t.Errorf("canonicalPrefix(../) error = %v, want ErrInvalidPath", err)
+	}
+}
+
+func TestProviderCanonicalPaths(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+	ctx := context.Background()
+	p := New(Config{ServiceName: "paths-test"})
+
+	if err := p.Set(ctx, "/db//password/", &vault.Secret{Value: "pw"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	secret, err := p.Get(ctx, "db/password")
+	if err != nil || secret.Value != "pw" || secret.Metadata.Path != "db/password" {
+		t.Errorf("Get() = %+v, %v; want canonical path", secret, err)
+	}
+	if ok, err := p.Exists(ctx, "db//password"); err != nil || !ok {
+		t.Errorf("Exists() = %v, %v; want true", ok, err)
+	}
+	paths, err := p.List(ctx, "db//")
+	if err != nil || !reflect.DeepEqual(paths, []string{"db/password"}) {
+		t.Errorf("List() = %v, %v", paths, err)
+	}
+
+	// NFC and NFD spellings name the same secret.
+	if err := p.Set(ctx, "cafe\u0301", &vault.Secret{Value: "latte"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if secret, err := p.Get(ctx, "caf\u00e9"); err != nil || secret.Value != "latte" {
+		t.Errorf("Get(NFC) = %v, %v; want value set with NFD", secret, err)
+	}
+
+	if err := p.Delete(ctx, "db/password/"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := p.Get(ctx, "db/password"); !errors.Is(err, vault.ErrSecretNotFound) {
+		t.Errorf("Get() after Delete error = %v, want ErrSecretNotFound", err)
+	}
+
+	for name, op := range map[string]func(path string) error{
+		"Get":    func(path string) error { _, err := p.Get(ctx, path); return err },
+		"Set":    func(path string) error { return p.Set(ctx, path, &vault.Secret{Value: "v"}) },
+		"Delete": func(path string) error { return p.Delete(ctx, path) },
+		"Exists": func(path string) error { _, err := p.Exists(ctx, path); return err },
+		"Rename": func(path string) error { return p.Rename(ctx, "caf\u00e9", path) },
+	} {
+		for _, path := range []string{"", "a/../b", "a\nb", indexKey} {
+			var pathErr *InvalidPathError
+			if err := op(path); !errors.As(err, &pathErr) {
+				t.Errorf("%s(%q) error = %v, want *InvalidPathError", name, path, err)
+			}
+		}
+	}
+	if _, err := p.List(ctx, "../"); !errors.Is(err, vault.ErrInvalidPath) {
+		t.Errorf("List(../) error = %v, want ErrInvalidPath", err)
+	}
+}
+
+func TestProviderCanonicalBatch(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+	ctx := context.Background()
+	p := New(Config{ServiceName: "paths-batch-test"})
+
+	results, err := p.SetMany(ctx, map[string]*vault.Secret{
+		"a//x": {Value: "1"},
+		"..":   {Value: "2"},
+	})
+	if !errors.Is(err, vault.ErrInvalidPath) {
+		t.Errorf("SetMany() error = %v, want ErrInvalidPath", err)
+	}
+	if len(results) != 2 || results[0].Path != ".." || results[1].Path != "a/x" || results[1].Err != nil {
+		t.Errorf("SetMany() results = %+v", results)
+	}
+
+	got, err := p.GetBatch(ctx, []string{"/a/x"})
+	if err != nil || got["a/x"] == nil || got["a/x"].Value != "1" {
+		t.Errorf("GetBatch() = %v, %v", got, err)
+	}
+}
+
+func TestProviderCanonicalBatchCollisions(t *testing.T) {
+	b := newMemoryBackend()
+	useBackend(t, b)
+	ctx := context.Background()
+	p := New(Config{ServiceName: "paths-collide-test"})
+
+	results, err := p.SetMany(ctx, map[string]*vault.Secret{
+		"a/b":  {Value: "1"},
+		"a//b": {Value: "2"},
+		"c":    {Value: "3"},
+	})
+	if !errors.Is(err, vault.ErrInvalidPath) {
+		t.Errorf("SetMany() error = %v, want ErrInvalidPath", err)
+	}
+	if len(results) != 3 || results[0].Path != "a//b" || results[1].Path != "a/b" ||
+		!errors.Is(results[0].Err, vault.ErrInvalidPath) || !errors.Is(results[1].Err, vault.ErrInvalidPath) || results[2].Err != nil {
+		t.Errorf("SetMany() results = %+v", results)
+	}
+	if n := b.count("Set", "a/b"); n != 0 {
+		t.Errorf("colliding paths written %d times, want 0", n)
+	}
+
+	_ = p.Set(ctx, "a/b", &vault.Secret{Value: "1"})
+	results, err = p.DeleteMany(ctx, []string{"a/b", "/a/b/", "c"})
+	if err != nil {
+		t.Fatalf("DeleteMany() error = %v", err)
+	}
+	if len(results) != 3 || results[0].Path != "a/b" || results[1].Path != "a/b" {
+		t.Errorf("DeleteMany() results = %+v", results)
+	}
+	if n := b.count("Delete", "a/b"); n != 1 {
+		t.Errorf("a/b deleted %d times, want once", n)
+	}
+}
+
+func TestProviderPathRulesConfig(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+	ctx := context.Background()
+	p := New(Config{ServiceName: "paths-rules-test", PathRules: PathRules{PreserveSlashes: true}})
+
+	if err := p.Set(ctx, "a//b", &vault.Secret{Value: "double"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if _, err := p.Get(ctx, "a/b"); !errors.Is(err, vault.ErrSecretNotFound) {
+		t.Errorf("Get(a/b) error = %v, want ErrSecretNotFound with PreserveSlashes", err)
+	}
+	if secret, err := p.Get(ctx, "a//b"); err != nil || secret.Value != "double" {
+		t.Errorf("Get(a//b) = %v, %v", secret, err)
+	}
+}
+
+func TestProviderMigratePaths(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+	ctx := context.Background()
+
+	// Secrets stored before canonicalization, under paths it changes.
+	legacy := New(Config{ServiceName: "paths-migrate-test", PathRules: PathRules{PreserveSlashes: true, SkipNormalization: true}})
+	for path, value := range map[string]string{"a//b": "ab", "/x": "x", "café": "latte", "dup": "kept", "dup/": "dropped"} {
+		if err := legacy.Set(ctx, path, &vault.Secret{Value: value}); err != nil {
+			t.Fatalf("Set(%q) error = %v", path, err)
+		}
+	}
+
+	p := New(Config{ServiceName: "paths-migrate-test"})
+	if _, err := p.Get(ctx, "a//b"); !errors.Is(err, vault.ErrSecretNotFound) {
+		t.Fatalf("Get() before migration error = %v, want ErrSecretNotFound", err)
+	}
+
+	moved, err := p.MigratePaths(ctx)
+	if !errors.Is(err, vault.ErrAlreadyExists) {
+		t.Errorf("MigratePaths() error = %v, want ErrAlreadyExists for dup/", err)
+	}
+	sort.Strings(moved)
+	if want := []string{"a/b", "caf\u00e9", "x"}; !reflect.DeepEqual(moved, want) {
+		t.Errorf("MigratePaths() = %v, want %v", moved, want)
+	}
+	for path, want := range map[string]string{"a//b": "ab", "/x": "x", "café": "latte", "dup": "kept"} {
+		if secret, err := p.Get(ctx, path); err != nil || secret.Value != want {
+			t.Errorf("Get(%q) after migration = %v, %v; want %q", path, secret, err, want)
+		}
+	}
+	paths, _ := p.List(ctx, "")
+	sort.Strings(paths)
+	if want := []string{"a/b", "caf\u00e9", "dup", "dup/", "x"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("List() after migration = %v, want %v", paths, want)
+	}
+
+	// Migrating again has nothing left to move.
+	if moved, err := p.MigratePaths(ctx); len(moved) != 0 || !errors.Is(err, vault.ErrAlreadyExists) {
+		t.Errorf("second MigratePaths() = %v, %v", moved, err)
+	}
+}
@@ -0,0 +1,159 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/agentplexus/omnivault/vault"
+	zkeyring "github.com/zalando/go-keyring"
+)
+
+// DeletePrefixOptions controls the behavior of Provider.DeletePrefix.
+type DeletePrefixOptions struct {
+	// DryRun reports which secrets would be deleted without deleting them.
+	DryRun bool
+
+	// MaxCount aborts the operation before deleting anything if more than
+	// MaxCount secrets match the prefix. Zero means no limit.
+	MaxCount int
+
+	// Confirm is called for each matching path; only paths for which it
+	// returns true are deleted. If nil, every matching path is deleted.
+	// It is also called during a dry run. It is called without the
+	// provider's locks held, so it may read the secret, e.g. with Get.
+	Confirm func(path string) bool
+}
+
+// DeletePrefix deletes every indexed secret whose path starts with prefix
+// and returns the paths that were deleted (or, for a dry run, would be).
+//
+// Backend deletes run in parallel, bounded by Config.BatchConcurrency, and
+// the index is updated with a single save. If some deletes fail, the
+// returned slice lists the ones that succeeded and the error joins the
+// failures. An empty prefix is rejected with vault.ErrInvalidPath, and
+// exceeding MaxCount returns ErrLimitExceeded.
+//
+// With Confirm set, the matches are confirmed first and looked up again
+// before deleting: confirmed paths removed in the meantime are skipped, and
+// paths added in the meantime are not deleted.
+func (p *Provider) DeletePrefix(ctx context.Context, prefix string, opts DeletePrefixOptions) ([]string, error) {
+	if err := p.ensureRecovered(ctx); err != nil {
+		return nil, vault.NewVaultError("DeletePrefix", prefix, p.Name(), err)
+	}
+
+	var confirmed map[string]bool
+	if opts.Confirm != nil {
+		matches, err := p.lockedPrefixMatches(ctx, prefix, opts.MaxCount)
+		if err != nil {
+			return nil, err
+		}
+		confirmed = make(map[string]bool, len(matches))
+		var targets []string
+		for _, path := range matches {
+			if opts.Confirm(path) {
+				confirmed[path] = true
+				targets = append(targets, path)
+			}
+		}
+		if opts.DryRun {
+			return targets, nil
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	_, matches, err := p.prefixMatches(ctx, prefix, opts.MaxCount)
+	if err != nil {
+		return nil, err
+	}
+	targets := matches
+	if confirmed != nil {
+		targets = nil
+		for _, path := range matches {
+			if confirmed[path] {
+				targets = append(targets, path)
+			}
+		}
+	}
+	if opts.DryRun {
+		return targets, nil
+	}
+
+	errs := make([]error, len(targets))
+	p.parallel(len(targets), func(i int) {
+		if err := ctx.Err(); err != nil {
+			errs[i] = vault.NewVaultError("DeletePrefix", targets[i], p.Name(), err)
+			return
+		}
+		_, errs[i] = p.handle(ctx, &Request{Op: "DeletePrefix", Path: targets[i]}, func(ctx context.Context, req *Request) (*Response, error) {
+			err := p.remove(ctx, targets[i])
+			if err != nil && !errors.Is(err, zkeyring.ErrNotFound) {
+				return nil, vault.NewVaultError("DeletePrefix", targets[i], p.Name(), err)
+			}
+			return nil, nil
+		})
+	})
+
+	deleted := make([]string, 0, len(targets))
+	for i, path := range targets {
+		if errs[i] == nil {
+			deleted = append(deleted, path)
+		}
+	}
+	if len(deleted) > 0 {
+		ctx, cancel := p.detach(ctx)
+		defer cancel()
+		p.updateIndex(ctx, nil, deleted)
+	}
+
+	return deleted, errors.Join(errs...)
+}
+
+// lockedPrefixMatches is prefixMatches, taking p.mu for reading.
+func (p *Provider) lockedPrefixMatches(ctx context.Context, prefix string, maxCount int) ([]string, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	_, matches, err := p.prefixMatches(ctx, prefix, maxCount)
+	return matches, err
+}
+
+// prefixMatches returns the canonical form of prefix and the indexed paths
+// starting with it, failing with ErrLimitExceeded if there are more than
+// maxCount of them. The caller must hold p.mu.
+func (p *Provider) prefixMatches(ctx context.Context, prefix string, maxCount int) (string, []string, error) {
+	if p.closed {
+		return "", nil, vault.NewVaultError("DeletePrefix", prefix, p.Name(), vault.ErrClosed)
+	}
+	prefix, err := p.canonicalPrefix("DeletePrefix", prefix)
+	if err != nil {
+		return "", nil, err
+	}
+	if prefix == "" {
+		return "", nil, vault.NewVaultError("DeletePrefix", prefix, p.Name(),
+			fmt.Errorf("%w: prefix must not be empty", vault.ErrInvalidPath))
+	}
+
+	var matches []string
+	for _, key := range p.loadIndex(ctx) {
+		if strings.HasPrefix(key, prefix) {
+			matches = append(matches, key)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return "", nil, vault.NewVaultError("DeletePrefix", prefix, p.Name(), err)
+	}
+	if maxCount > 0 && len(matches) > maxCount {
+		return "", nil, vault.NewVaultError("DeletePrefix", prefix, p.Name(),
+			fmt.Errorf("%w: %d secrets match, limit is %d", ErrLimitExceeded, len(matches), maxCount))
+	}
+	return prefix, matches, nil
+}
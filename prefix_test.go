@@ -0,0 +1,154 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+func setupDeletePrefix(t *testing.T) (*Provider, *memoryBackend) {
+	t.Helper()
+	b := newMemoryBackend()
+	useBackend(t, b)
+
+	p := New(Config{ServiceName: "test-delete-prefix"})
+	t.Cleanup(func() { _ = p.Close() })
+
+	_, err := p.SetMany(context.Background(), map[string]*vault.Secret{
+		"myapp/db":      {Value: "1"},
+		"myapp/api":     {Value: "2"},
+		"myapp/cache/x": {Value: "3"},
+		"other/db":      {Value: "4"},
+	})
+	if err != nil {
+		t.Fatalf("SetMany failed: %v", err)
+	}
+	return p, b
+}
+
+func TestProvider_DeletePrefix(t *testing.T) {
+	ctx := context.Background()
+	p, b := setupDeletePrefix(t)
+	indexWrites := b.count("Set", indexKey)
+
+	deleted, err := p.DeletePrefix(ctx, "myapp/", DeletePrefixOptions{})
+	if err != nil {
+		t.Fatalf("DeletePrefix failed: %v", err)
+	}
+	sort.Strings(deleted)
+	if len(deleted) != 3 || deleted[0] != "myapp/api" {
+		t.Errorf("unexpected deleted paths %v", deleted)
+	}
+	if n := b.count("Set", indexKey) - indexWrites; n != 1 {
+		t.Errorf("expected a single index write, got %d", n)
+	}
+
+	list, _ := p.List(ctx, "")
+	if len(list) != 1 || list[0] != "other/db" {
+		t.Errorf("expected only other/db to remain, got %v", list)
+	}
+	if exists, _ := p.Exists(ctx, "myapp/db"); exists {
+		t.Error("expected myapp/db to be deleted")
+	}
+}
+
+func TestProvider_DeletePrefix_DryRun(t *testing.T) {
+	ctx := context.Background()
+	p, _ := setupDeletePrefix(t)
+
+	paths, err := p.DeletePrefix(ctx, "myapp/", DeletePrefixOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("DeletePrefix failed: %v", err)
+	}
+	if len(paths) != 3 {
+		t.Errorf("expected 3 candidate paths, got %v", paths)
+	}
+	if list, _ := p.List(ctx, "myapp/"); len(list) != 3 {
+		t.Errorf("expected dry run to delete nothing, got %v", list)
+	}
+}
+
+func TestProvider_DeletePrefix_MaxCount(t *testing.T) {
+	ctx := context.Background()
+	p, _ := setupDeletePrefix(t)
+
+	_, err := p.DeletePrefix(ctx, "myapp/", DeletePrefixOptions{MaxCount: 2})
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected ErrLimitExceeded, got %v", err)
+	}
+	if list, _ := p.List(ctx, "myapp/"); len(list) != 3 {
+		t.Errorf("expected nothing to be deleted, got %v", list)
+	}
+}
+
+func TestProvider_DeletePrefix_Confirm(t *testing.T) {
+	ctx := context.Background()
+	p, _ := setupDeletePrefix(t)
+
+	var asked []string
+	deleted, err := p.DeletePrefix(ctx, "myapp/", DeletePrefixOptions{
+		Confirm: func(path string) bool {
+			asked = append(asked, path)
+			return path != "myapp/db"
+		},
+	})
+	if err != nil {
+		t.Fatalf("DeletePrefix failed: %v", err)
+	}
+	if len(asked) != 3 {
+		t.Errorf("expected confirm to be called 3 times, got %v", asked)
+	}
+	if len(deleted) != 2 {
+		t.Errorf("expected 2 deleted paths, got %v", deleted)
+	}
+	if exists, _ := p.Exists(ctx, "myapp/db"); !exists {
+		t.Error("expected unconfirmed path to remain")
+	}
+}
+
+func TestProvider_DeletePrefix_ConfirmReads(t *testing.T) {
+	ctx := context.Background()
+	p, _ := setupDeletePrefix(t)
+
+	// Confirm may use the provider, and the matches may change while it
+	// runs: a confirmed path removed, and a new one added.
+	deleted, err := p.DeletePrefix(ctx, "myapp/", DeletePrefixOptions{
+		Confirm: func(path string) bool {
+			secret, err := p.Get(ctx, path)
+			if err != nil {
+				t.Errorf("Get(%q) in Confirm error = %v", path, err)
+				return false
+			}
+			switch path {
+			case "myapp/api":
+				_ = p.Delete(ctx, "myapp/api")
+			case "myapp/db":
+				_ = p.Set(ctx, "myapp/new", &vault.Secret{Value: "5"})
+			}
+			return secret.Value != "3"
+		},
+	})
+	if err != nil {
+		t.Fatalf("DeletePrefix failed: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != "myapp/db" {
+		t.Errorf("expected only myapp/db to be deleted, got %v", deleted)
+	}
+	for path, want := range map[string]bool{"myapp/db": false, "myapp/cache/x": true, "myapp/new": true} {
+		if exists, _ := p.Exists(ctx, path); exists != want {
+			t.Errorf("Exists(%q) = %v, want %v", path, exists, want)
+		}
+	}
+}
+
+func TestProvider_DeletePrefix_EmptyPrefix(t *testing.T) {
+	p, _ := setupDeletePrefix(t)
+
+	_, err := p.DeletePrefix(context.Background(), "", DeletePrefixOptions{})
+	if !errors.Is(err, vault.ErrInvalidPath) {
+		t.Errorf("expected ErrInvalidPath, got %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+// DefaultProfileEnv is the environment variable that selects the active
+// profile when Config.ProfileEnv is not set.
+const DefaultProfileEnv = "OMNIVAULT_PROFILE"
+
+// ErrUnknownProfile is returned by every operation of a provider whose
+// selected profile, or one of its fallbacks, is not defined in
+// Config.Profiles or forms a fallback cycle.
+var ErrUnknownProfile = errors.New("unknown profile")
+
+// ErrProfileConflict is returned by every operation of a provider whose
+// Config.Profiles has two profiles storing keys under the same service name
+// where the prefix of one starts with the prefix of the other, so that one
+// could read and overwrite the other's secrets and bookkeeping.
+var ErrProfileConflict = errors.New("conflicting profiles")
+
+// Profile describes where the secrets of one environment, such as "dev" or
+// "prod", are stored. Paths are the same in every profile; the profile
+// decides which keyring entries they map to.
+type Profile struct {
+	// ServiceName stores the profile's secrets under its own service name.
+	// If empty, Config.ServiceName is used.
+	ServiceName string
+
+	// Prefix is prepended to every key the profile stores, so several
+	// profiles can share a service name. A trailing slash is added if
+	// missing. Each prefix has its own index. Profiles sharing a service
+	// name must have prefixes that don't start with one another; in
+	// particular, at most one of them may have an empty prefix.
+	Prefix string
+
+	// Fallback names the profile that reads fall back to when a secret is
+	// not found in this profile, e.g. a "base" profile holding secrets shared
+	// by all environments. Fallbacks can chain. Writes and deletes never
+	// reach the fallback.
+	Fallback string
+}
+
+// Profile returns the name of the active profile, or "" if profiles are
+// not configured.
+func (p *Provider) Profile() string {
+	return p.profile
+}
+
+// ListEffective returns the paths matching prefix that Get would find: the
+// union of List across the active profile and its fallback chain, sorted.
+// Without a fallback it returns the same paths as List.
+func (p *Provider) ListEffective(ctx context.Context, prefix string) ([]string, error) {
+	seen := make(map[string]bool)
+	var results []string
+	for profile := p; profile != nil; profile = profile.fallback {
+		paths, err := profile.List(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			if !seen[path] {
+				seen[path] = true
+				results = append(results, path)
+			}
+		}
+	}
+	sort.Strings(results)
+	return results, nil
+}
+
+// selectProfile returns the name of the profile selected by config: the
+// value of the profile environment variable if set, else Config.Profile.
+// Profiles are disabled, and the variable ignored, when none are defined.
+func selectProfile(config Config) string {
+	if len(config.Profiles) == 0 {
+		return ""
+	}
+	env := config.ProfileEnv
+	if env == "" {
+		env = DefaultProfileEnv
+	}
+	if name := os.Getenv(env); name != "" {
+		return name
+	}
+	return config.Profile
+}
+
+// newProvider creates the provider for the named profile and, recursively,
+// its fallbacks. visited holds the profiles already in the chain.
+func newProvider(config Config, name string, visited map[string]bool) *Provider {
+	p := &Provider{config: config, profile: name, cache: newSecretCache(config.Cache), done: make(chan struct{})}
+	p.telemetry = newTelemetry(p)
+	p.log = newLogger(config)
+	if len(config.PathKey) > 0 {
+		paths, err := newPathCipher(config.PathKey)
+		if err != nil {
+			p.backend = errBackend{err}
+			p.logBackend()
+			return p
+		}
+		p.paths = paths
+	}
+
+	profile, ok := config.Profiles[name]
+	switch {
+	case name == "":
+		p.backend = newBackend(config)
+	case !ok:
+		p.backend = errBackend{fmt.Errorf("%w %q", ErrUnknownProfile, name)}
+	case visited[name]:
+		p.backend = errBackend{fmt.Errorf("%w %q: fallback cycle", ErrUnknownProfile, name)}
+	default:
+		if err := checkProfiles(config); err != nil {
+			p.backend = errBackend{err}
+			break
+		}
+		if profile.ServiceName != "" {
+			p.config.ServiceName = profile.ServiceName
+		}
+		prefix, err := scopePrefix("", profile.Prefix)
+		if err != nil {
+			p.backend = errBackend{fmt.Errorf("profile %q: %w", name, err)}
+			break
+		}
+		p.keyPrefix = prefix
+		p.backend = newBackend(p.config)
+		if profile.Fallback != "" {
+			visited[name] = true
+			// Lookups in the fallback are part of the operation passing
+			// through this provider's middleware, not separate operations.
+			fallback := config
+			fallback.Middleware = nil
+			p.fallback = newProvider(fallback, profile.Fallback, visited)
+		}
+	}
+
+	p.logBackend()
+	return p
+}
+
+// checkProfiles returns an error matching ErrProfileConflict if two of
+// config's profiles store keys under the same service name with one prefix
+// starting with the other. Profiles with an invalid prefix are left to fail
+// on their own when selected.
+func checkProfiles(config Config) error {
+	type location struct{ name, service, prefix string }
+	var locations []location
+	for name, profile := range config.Profiles {
+		prefix, err := scopePrefix("", profile.Prefix)
+		if err != nil {
+			continue
+		}
+		service := profile.ServiceName
+		if service == "" {
+			service = config.ServiceName
+		}
+		locations = append(locations, location{name, service, prefix})
+	}
+	sort.Slice(locations, func(i, j int) bool { return locations[i].name < locations[j].name })
+
+	for i, a := range locations {
+		for _, b := range locations[i+1:] {
+			if a.service == b.service && (strings.HasPrefix(a.prefix, b.prefix) || strings.HasPrefix(b.prefix, a.prefix)) {
+				return fmt.Errorf("%w %q and %q: both store under service %q, with prefixes %q and %q",
+					ErrProfileConflict, a.name, b.name, a.service, a.prefix, b.prefix)
+			}
+		}
+	}
+	return nil
+}
+
+// logBackend logs the backend selected for p, or why there is none.
+func (p *Provider) logBackend() {
+	if b, ok := p.backend.(errBackend); ok {
+		p.log.Warn("keyring: configuration unusable, every operation will fail",
+			slog.String("profile", p.profile), slog.Any("error", b.err))
+		return
+	}
+	p.log.Debug("keyring: backend selected",
+		slog.String("backend", p.Backend()),
+		slog.String("collection", p.config.Collection),
+		slog.String("profile", p.profile),
+		slog.String("service", p.config.ServiceName),
+		slog.String("prefix", p.keyPrefix),
+		slog.Bool("obfuscated", p.paths != nil),
+		slog.Bool("cache", p.cache != nil),
+		slog.Bool("fallback", p.fallback != nil))
+}
+
+// logFallback logs that path wasn't found in p and is looked up in its
+// fallback profile.
+func (p *Provider) logFallback(ctx context.Context, path string) {
+	p.log.DebugContext(ctx, "keyring: secret not found, trying fallback profile", p.logPath(path),
+		slog.String("profile", p.profile), slog.String("fallback", p.fallback.profile))
+}
+
+// fallbackGet reads path from the fallback profile. The caller must hold
+// p.mu.
+func (p *Provider) fallbackGet(ctx context.Context, op, path string) (*vault.Secret, error) {
+	f := p.fallback
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.closed {
+		return nil, vault.NewVaultError(op, path, f.Name(), vault.ErrClosed)
+	}
+	return f.get(ctx, op, path)
+}
+
+// errBackend fails every call with err. It stands in for the keyring when
+// the configuration is unusable, e.g. it selects an unknown profile.
+type errBackend struct{ err error }
+
+func (b errBackend) Get(service, user string) (string, error) {
+	return "", b.err
+}
+
+func (b errBackend) Set(service, user, value string) error {
+	return b.err
+}
+
+func (b errBackend) Delete(service, user string) error {
+	return b.err
+}
@@ -0,0 +1,225 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+func profileConfig() Config {
+	return Config{
+		ServiceName: "profile-test",
+		Profiles: map[string]Profile{
+			"base":    {ServiceName: "profile-test-base"},
+			"staging": {Prefix: "staging", Fallback: "base"},
+			"prod":    {ServiceName: "profile-test-prod", Fallback: "base"},
+		},
+	}
+}
+
+func TestProfileSelection(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+
+	config := profileConfig()
+	config.Profile = "prod"
+	p := New(config)
+	if p.Profile() != "prod" || p.ServiceName() != "profile-test-prod" {
+		t.Errorf("Profile() = %q, ServiceName() = %q; want prod, profile-test-prod", p.Profile(), p.ServiceName())
+	}
+
+	t.Setenv(DefaultProfileEnv, "staging")
+	p = New(config)
+	if p.Profile() != "staging" || p.ServiceName() != "profile-test" {
+		t.Errorf("Profile() = %q, ServiceName() = %q; want staging, profile-test", p.Profile(), p.ServiceName())
+	}
+
+	config.ProfileEnv = "APP_ENV"
+	t.Setenv("APP_ENV", "base")
+	if p := New(config); p.Profile() != "base" {
+		t.Errorf("Profile() = %q with ProfileEnv set, want base", p.Profile())
+	}
+
+	// Without profiles the variable is ignored.
+	if p := New(Config{ServiceName: "profile-test"}); p.Profile() != "" {
+		t.Errorf("Profile() = %q without profiles, want empty", p.Profile())
+	}
+}
+
+func TestProfileIsolation(t *testing.T) {
+	b := newMemoryBackend()
+	useBackend(t, b)
+	ctx := context.Background()
+
+	config := profileConfig()
+	config.Profile = "staging"
+	staging := New(config)
+	config.Profile = "prod"
+	prod := New(config)
+
+	if err := staging.Set(ctx, "db/password", &vault.Secret{Value: "staging-pw"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := prod.Set(ctx, "db/password", &vault.Secret{Value: "prod-pw"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	if v, ok := b.store["profile-test"]["staging/db/password"]; !ok || v != "staging-pw" {
+		t.Errorf("staging stored %q, %v under its prefix", v, ok)
+	}
+	if v := b.store["profile-test-prod"]["db/password"]; v != "prod-pw" {
+		t.Errorf("prod stored %q under its service name", v)
+	}
+
+	for p, want := range map[*Provider]string{staging: "staging-pw", prod: "prod-pw"} {
+		secret, err := p.Get(ctx, "db/password")
+		if err != nil || secret.Value != want {
+			t.Errorf("%s: Get() = %v, %v; want %q", p.Profile(), secret, err, want)
+		}
+		paths, err := p.List(ctx, "")
+		if err != nil || !reflect.DeepEqual(paths, []string{"db/password"}) {
+			t.Errorf("%s: List() = %v, %v", p.Profile(), paths, err)
+		}
+	}
+}
+
+func TestProfileFallback(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+	ctx := context.Background()
+
+	config := profileConfig()
+	config.Profile = "base"
+	base := New(config)
+	config.Profile = "staging"
+	staging := New(config)
+
+	if err := base.Set(ctx, "shared/license", &vault.Secret{Value: "base-license"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := base.Set(ctx, "db/password", &vault.Secret{Value: "base-pw"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := staging.Set(ctx, "db/password", &vault.Secret{Value: "staging-pw"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	secret, err := staging.Get(ctx, "shared/license")
+	if err != nil || secret.Value != "base-license" {
+		t.Errorf("Get() = %v, %v; want value from base", secret, err)
+	}
+	secret, err = staging.Get(ctx, "db/password")
+	if err != nil || secret.Value != "staging-pw" {
+		t.Errorf("Get() = %v, %v; want staging override", secret, err)
+	}
+	if ok, err := staging.Exists(ctx, "shared/license"); err != nil || !ok {
+		t.Errorf("Exists() = %v, %v; want true via fallback", ok, err)
+	}
+	secure, err := staging.GetSecure(ctx, "shared/license")
+	if err != nil || string(secure.Value()) != "base-license" {
+		t.Errorf("GetSecure() = %v; want value from base", err)
+	} else {
+		secure.Destroy()
+	}
+	if _, err := staging.Get(ctx, "missing"); !errors.Is(err, vault.ErrSecretNotFound) {
+		t.Errorf("Get(missing) error = %v, want ErrSecretNotFound", err)
+	}
+
+	paths, err := staging.List(ctx, "")
+	if err != nil || !reflect.DeepEqual(paths, []string{"db/password"}) {
+		t.Errorf("List() = %v, %v; want only the profile's own paths", paths, err)
+	}
+	paths, err = staging.ListEffective(ctx, "")
+	if err != nil || !reflect.DeepEqual(paths, []string{"db/password", "shared/license"}) {
+		t.Errorf("ListEffective() = %v, %v", paths, err)
+	}
+
+	// Deleting through staging leaves base untouched.
+	if err := staging.Delete(ctx, "shared/license"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := base.Get(ctx, "shared/license"); err != nil {
+		t.Errorf("base Get() after staging Delete error = %v", err)
+	}
+}
+
+func TestProfileUnknown(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+	ctx := context.Background()
+
+	config := profileConfig()
+	config.Profile = "qa"
+	p := New(config)
+	if _, err := p.Get(ctx, "x"); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("Get() error = %v, want ErrUnknownProfile", err)
+	}
+	if err := p.Set(ctx, "x", &vault.Secret{Value: "v"}); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("Set() error = %v, want ErrUnknownProfile", err)
+	}
+
+	config.Profiles["a"] = Profile{Prefix: "a", Fallback: "b"}
+	config.Profiles["b"] = Profile{Prefix: "b", Fallback: "a"}
+	config.Profile = "a"
+	p = New(config)
+	if _, err := p.Get(ctx, "x"); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("Get() with fallback cycle error = %v, want ErrUnknownProfile", err)
+	}
+}
+
+func TestProfileConflict(t *testing.T) {
+	b := newMemoryBackend()
+	useBackend(t, b)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		profiles map[string]Profile
+	}{
+		{"empty prefix", map[string]Profile{"dev": {Prefix: "dev"}, "shared": {}}},
+		{"same prefix", map[string]Profile{"dev": {Prefix: "dev"}, "dev2": {Prefix: "dev/"}}},
+		{"nested prefix", map[string]Profile{"dev": {Prefix: "dev"}, "devx": {Prefix: "dev/x"}}},
+		{"explicit service name", map[string]Profile{"dev": {Prefix: "dev"}, "other": {ServiceName: "profile-test"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name := range tt.profiles {
+				p := New(Config{ServiceName: "profile-test", Profiles: tt.profiles, Profile: name})
+				if err := p.Set(ctx, "dev/"+indexKey, &vault.Secret{Value: "x"}); !errors.Is(err, ErrProfileConflict) {
+					t.Errorf("%s: Set() error = %v, want ErrProfileConflict", name, err)
+				}
+				if _, err := p.Get(ctx, "x"); !errors.Is(err, ErrProfileConflict) {
+					t.Errorf("%s: Get() error = %v, want ErrProfileConflict", name, err)
+				}
+			}
+		})
+	}
+	if len(b.store) != 0 {
+		t.Errorf("conflicting profiles wrote to the keyring: %v", b.store)
+	}
+
+	// Prefixes that merely share leading characters don't conflict, nor do
+	// profiles under different service names.
+	config := Config{ServiceName: "profile-test", Profile: "dev", Profiles: map[string]Profile{
+		"dev":         {Prefix: "dev"},
+		"development": {Prefix: "development"},
+		"prod":        {ServiceName: "profile-test-prod"},
+	}}
+	if err := New(config).Set(ctx, "x", &vault.Secret{Value: "v"}); err != nil {
+		t.Errorf("Set() error = %v", err)
+	}
+}
+
+func TestProfileClose(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+
+	config := profileConfig()
+	config.Profile = "prod"
+	p := New(config)
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if !p.fallback.closed {
+		t.Error("fallback provider not closed")
+	}
+}
@@ -0,0 +1,191 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	dbus "github.com/godbus/dbus/v5"
+)
+
+// Default retry policy values, used for fields left at zero.
+const (
+	DefaultRetryAttempts   = 3
+	DefaultRetryBackoff    = 100 * time.Millisecond
+	DefaultRetryMaxBackoff = 2 * time.Second
+	DefaultRetryMultiplier = 2.0
+	DefaultRetryJitter     = 0.2
+)
+
+// RetryPolicy controls how backend operations that fail transiently are
+// retried. It applies to every keyring call, including index and journal
+// maintenance. Fields left at zero use the package defaults.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts per backend call,
+	// including the first. Set it to 1 to disable retries.
+	// Default: 3
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry.
+	// Default: 100ms
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts.
+	// Default: 2s
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor the delay grows by after each retry.
+	// Default: 2
+	Multiplier float64
+
+	// Jitter randomizes each delay by up to this fraction in either
+	// direction, so that clients started together don't retry in lockstep.
+	// Default: 0.2
+	Jitter float64
+
+	// Retryable reports whether a backend error is worth retrying.
+	// Default: IsRetryable
+	Retryable func(err error) bool
+}
+
+// RetryAttempt describes a failed backend call that is about to be retried.
+type RetryAttempt struct {
+	// Op is the backend operation: "Get", "Set" or "Delete".
+	Op string
+
+	// Path is the key the operation was applied to.
+	Path string
+
+	// Attempt is the number of the attempt that failed, starting at 1.
+	Attempt int
+
+	// Delay is how long the provider waits before the next attempt.
+	Delay time.Duration
+
+	// Err is the error returned by the failed attempt.
+	Err error
+}
+
+// withDefaults returns the policy with zero fields replaced by defaults.
+func (r RetryPolicy) withDefaults() RetryPolicy {
+	if r.MaxAttempts <= 0 {
+		r.MaxAttempts = DefaultRetryAttempts
+	}
+	if r.InitialBackoff <= 0 {
+		r.InitialBackoff = DefaultRetryBackoff
+	}
+	if r.MaxBackoff <= 0 {
+		r.MaxBackoff = DefaultRetryMaxBackoff
+	}
+	if r.Multiplier < 1 {
+		r.Multiplier = DefaultRetryMultiplier
+	}
+	if r.Jitter <= 0 {
+		r.Jitter = DefaultRetryJitter
+	}
+	if r.Retryable == nil {
+		r.Retryable = IsRetryable
+	}
+	return r
+}
+
+// backoff returns the delay before the retry following the given attempt.
+func (r RetryPolicy) backoff(attempt int) time.Duration {
+	delay := float64(r.InitialBackoff)
+	for i := 1; i < attempt && delay < float64(r.MaxBackoff); i++ {
+		delay *= r.Multiplier
+	}
+	delay = min(delay, float64(r.MaxBackoff))
+	delay *= 1 + r.Jitter*(2*rand.Float64()-1)
+	return time.Duration(delay)
+}
+
+// retry calls fn until it succeeds, fails with an error that isn't
+// retryable, runs out of attempts, or ctx is done.
+func (p *Provider) retry(ctx context.Context, op, path string, fn func() error) error {
+	policy := p.config.Retry
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= policy.MaxAttempts || !policy.Retryable(err) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+
+		delay := policy.backoff(attempt)
+		p.log.DebugContext(ctx, "keyring: retrying backend call", slog.String("op", op), p.logPath(path),
+			slog.Int("attempt", attempt), slog.Duration("delay", delay), slog.Any("error", err))
+		if p.config.OnRetry != nil {
+			p.config.OnRetry(RetryAttempt{Op: op, Path: path, Attempt: attempt, Delay: delay, Err: err})
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// transientDBusErrors are D-Bus errors seen while the session bus or the
+// keyring daemon is starting up or restarting.
+var transientDBusErrors = map[string]bool{
+	"org.freedesktop.DBus.Error.ServiceUnknown": true,
+	"org.freedesktop.DBus.Error.NameHasNoOwner": true,
+	"org.freedesktop.DBus.Error.NoReply":        true,
+	"org.freedesktop.DBus.Error.Timeout":        true,
+	"org.freedesktop.DBus.Error.TimedOut":       true,
+	"org.freedesktop.DBus.Error.NoServer":       true,
+	"org.freedesktop.DBus.Error.Disconnected":   true,
+	"org.freedesktop.DBus.Error.LimitsExceeded": true,
+	"org.freedesktop.DBus.Error.UnknownObject":  true,
+}
+
+// IsRetryable reports whether err looks like a transient backend failure,
+// such as the keyring daemon not having started yet or a dropped D-Bus
+// connection. Missing secrets, oversized values and context errors are
+// never retryable.
+func IsRetryable(err error) bool {
+	if err == nil || isContextError(err) {
+		return false
+	}
+
+	if name, ok := dbusErrorName(err); ok {
+		return transientDBusErrors[name] ||
+			strings.HasPrefix(name, "org.freedesktop.DBus.Error.Spawn.")
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, dbus.ErrClosed) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// dbusErrorName returns the name of the D-Bus error wrapped by err, if any.
+func dbusErrorName(err error) (string, bool) {
+	var dbusErr dbus.Error
+	if errors.As(err, &dbusErr) {
+		return dbusErr.Name, true
+	}
+	var dbusErrPtr *dbus.Error
+	if errors.As(err, &dbusErrPtr) && dbusErrPtr != nil {
+		return dbusErrPtr.Name, true
+	}
+	return "", false
+}
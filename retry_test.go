@@ -0,0 +1,185 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivault/vault"
+	dbus "github.com/godbus/dbus/v5"
+	zkeyring "github.com/zalando/go-keyring"
+)
+
+var errDaemonStarting = dbus.Error{Name: "org.freedesktop.DBus.Error.ServiceUnknown"}
+
+func isDaemonStarting(err error) bool {
+	name, _ := dbusErrorName(err)
+	return name == errDaemonStarting.Name
+}
+
+func TestProvider_Retry(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBackend()
+	useBackend(t, b)
+
+	var attempts []RetryAttempt
+	p := New(Config{
+		ServiceName: "test-retry",
+		Retry:       RetryPolicy{InitialBackoff: time.Millisecond},
+		OnRetry:     func(a RetryAttempt) { attempts = append(attempts, a) },
+	})
+	defer p.Close()
+
+	_ = p.Set(ctx, "key", &vault.Secret{Value: "v"})
+
+	failures := 2
+	b.failWith(func(op, _, user string) error {
+		if op == "Get" && user == "key" && failures > 0 {
+			failures--
+			return errDaemonStarting
+		}
+		return nil
+	})
+
+	secret, err := p.Get(ctx, "key")
+	if err != nil || secret.Value != "v" {
+		t.Fatalf("expected Get to succeed after retries, got %v, %v", secret, err)
+	}
+	if len(attempts) != 2 {
+		t.Fatalf("expected 2 retries, got %d", len(attempts))
+	}
+	for i, a := range attempts {
+		if a.Op != "Get" || a.Path != "key" || a.Attempt != i+1 || !isDaemonStarting(a.Err) {
+			t.Errorf("unexpected retry attempt %d: %+v", i, a)
+		}
+	}
+}
+
+func TestProvider_Retry_GivesUp(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBackend()
+	useBackend(t, b)
+
+	p := New(Config{
+		ServiceName: "test-retry-give-up",
+		Retry:       RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Millisecond},
+	})
+	defer p.Close()
+
+	b.failWith(func(op, _, user string) error {
+		if user == "key" {
+			return errDaemonStarting
+		}
+		return nil
+	})
+
+	if err := p.Set(ctx, "key", &vault.Secret{Value: "v"}); !isDaemonStarting(err) {
+		t.Errorf("expected the last error, got %v", err)
+	}
+	if n := b.count("Set", "key"); n != 4 {
+		t.Errorf("expected 4 attempts, got %d", n)
+	}
+}
+
+func TestProvider_Retry_NotRetryable(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBackend()
+	useBackend(t, b)
+
+	retries := 0
+	p := New(Config{
+		ServiceName: "test-retry-permanent",
+		Retry:       RetryPolicy{InitialBackoff: time.Millisecond},
+		OnRetry:     func(RetryAttempt) { retries++ },
+	})
+	defer p.Close()
+
+	if _, err := p.Get(ctx, "missing"); !errors.Is(err, vault.ErrSecretNotFound) {
+		t.Errorf("expected ErrSecretNotFound, got %v", err)
+	}
+	if n := b.count("Get", "missing"); n != 1 {
+		t.Errorf("expected a single attempt for a missing secret, got %d", n)
+	}
+	if retries != 0 {
+		t.Errorf("expected no retries, got %d", retries)
+	}
+}
+
+func TestProvider_Retry_ContextDone(t *testing.T) {
+	b := newMemoryBackend()
+	useBackend(t, b)
+
+	p := New(Config{
+		ServiceName: "test-retry-ctx",
+		Retry:       RetryPolicy{MaxAttempts: 100, InitialBackoff: time.Hour},
+	})
+	defer p.Close()
+
+	b.failWith(func(string, string, string) error { return errDaemonStarting })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.Get(ctx, "key"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Jitter:         0.1,
+	}.withDefaults()
+
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{5, time.Second},
+		{50, time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			delay := policy.backoff(tt.attempt)
+			lo := time.Duration(float64(tt.base) * 0.9)
+			hi := time.Duration(float64(tt.base) * 1.1)
+			if delay < lo || delay > hi {
+				t.Errorf("attempt %d: delay %v outside [%v, %v]", tt.attempt, delay, lo, hi)
+			}
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"not found", zkeyring.ErrNotFound, false},
+		{"too big", zkeyring.ErrSetDataTooBig, false},
+		{"canceled", context.Canceled, false},
+		{"deadline", fmt.Errorf("get: %w", context.DeadlineExceeded), false},
+		{"service unknown", errDaemonStarting, true},
+		{"no reply pointer", &dbus.Error{Name: "org.freedesktop.DBus.Error.NoReply"}, true},
+		{"spawn failed", dbus.Error{Name: "org.freedesktop.DBus.Error.Spawn.ChildExited"}, true},
+		{"access denied", dbus.Error{Name: "org.freedesktop.DBus.Error.AccessDenied"}, false},
+		{"connection refused", fmt.Errorf("dial: %w", syscall.ECONNREFUSED), true},
+		{"connection closed", dbus.ErrClosed, true},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
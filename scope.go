@@ -0,0 +1,288 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+// ScopedVault is a view of a Provider restricted to the secrets under a
+// path prefix. It is created with Provider.WithPrefix.
+//
+// Paths passed to a ScopedVault are relative to its prefix, and List returns
+// relative paths. Every operation goes through the parent provider, so the
+// scope shares its lock, index and configuration.
+type ScopedVault struct {
+	parent *Provider
+	prefix string
+	err    error // invalid prefix, reported by every operation
+	closed atomic.Bool
+}
+
+// WithPrefix returns a vault that prepends prefix to every path and only
+// sees secrets under it, e.g. WithPrefix("payments/") maps "api-key" to
+// "payments/api-key". A trailing slash is added to prefix if missing, and
+// the prefix is canonicalized with Config.PathRules like any path.
+//
+// Paths that would escape the scope, such as "../other", are rejected with
+// vault.ErrInvalidPath, as is every operation if prefix itself is invalid.
+func (p *Provider) WithPrefix(prefix string) *ScopedVault {
+	s := &ScopedVault{parent: p}
+	s.prefix, s.err = p.scopePrefix("", prefix)
+	return s
+}
+
+// WithPrefix returns a vault scoped to prefix within this scope.
+func (s *ScopedVault) WithPrefix(prefix string) *ScopedVault {
+	scoped := &ScopedVault{parent: s.parent, err: s.err}
+	if scoped.err == nil {
+		scoped.prefix, scoped.err = s.parent.scopePrefix(s.prefix, prefix)
+	}
+	return scoped
+}
+
+// Prefix returns the full path prefix of the scope.
+func (s *ScopedVault) Prefix() string {
+	return s.prefix
+}
+
+// Get retrieves the secret at path within the scope.
+// The returned secret's metadata carries the full path.
+func (s *ScopedVault) Get(ctx context.Context, path string) (*vault.Secret, error) {
+	full, err := s.resolve("Get", path)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := s.parent.Get(ctx, full)
+	return secret, s.unscope(err)
+}
+
+// Set stores a secret at path within the scope.
+func (s *ScopedVault) Set(ctx context.Context, path string, secret *vault.Secret) error {
+	full, err := s.resolve("Set", path)
+	if err != nil {
+		return err
+	}
+	return s.unscope(s.parent.Set(ctx, full, secret))
+}
+
+// Delete removes the secret at path within the scope.
+func (s *ScopedVault) Delete(ctx context.Context, path string) error {
+	full, err := s.resolve("Delete", path)
+	if err != nil {
+		return err
+	}
+	return s.unscope(s.parent.Delete(ctx, full))
+}
+
+// Exists reports whether a secret exists at path within the scope.
+func (s *ScopedVault) Exists(ctx context.Context, path string) (bool, error) {
+	full, err := s.resolve("Exists", path)
+	if err != nil {
+		return false, err
+	}
+	exists, err := s.parent.Exists(ctx, full)
+	return exists, s.unscope(err)
+}
+
+// List returns the paths within the scope that start with prefix,
+// relative to the scope.
+func (s *ScopedVault) List(ctx context.Context, prefix string) ([]string, error) {
+	if err := s.check("List", prefix); err != nil {
+		return nil, err
+	}
+	if err := validateScopedPath(prefix); err != nil {
+		return nil, vault.NewVaultError("List", prefix, s.Name(), err)
+	}
+
+	paths, err := s.parent.List(ctx, s.prefix+prefix)
+	if err != nil {
+		return nil, s.unscope(err)
+	}
+	for i, path := range paths {
+		paths[i] = strings.TrimPrefix(path, s.prefix)
+	}
+	return paths, nil
+}
+
+// GetBatch implements vault.BatchVault.
+func (s *ScopedVault) GetBatch(ctx context.Context, paths []string) (map[string]*vault.Secret, error) {
+	full, err := s.resolveAll("GetBatch", paths)
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := s.parent.GetBatch(ctx, full)
+	if secrets == nil {
+		return nil, s.unscope(err)
+	}
+	scoped := make(map[string]*vault.Secret, len(secrets))
+	for path, secret := range secrets {
+		scoped[strings.TrimPrefix(path, s.prefix)] = secret
+	}
+	return scoped, s.unscope(err)
+}
+
+// SetBatch implements vault.BatchVault.
+func (s *ScopedVault) SetBatch(ctx context.Context, secrets map[string]*vault.Secret) error {
+	if err := s.check("SetBatch", ""); err != nil {
+		return err
+	}
+	full := make(map[string]*vault.Secret, len(secrets))
+	for path, secret := range secrets {
+		resolved, err := s.resolve("SetBatch", path)
+		if err != nil {
+			return err
+		}
+		full[resolved] = secret
+	}
+	return s.unscope(s.parent.SetBatch(ctx, full))
+}
+
+// DeleteBatch implements vault.BatchVault.
+func (s *ScopedVault) DeleteBatch(ctx context.Context, paths []string) error {
+	full, err := s.resolveAll("DeleteBatch", paths)
+	if err != nil {
+		return err
+	}
+	return s.unscope(s.parent.DeleteBatch(ctx, full))
+}
+
+// Name returns the provider name.
+func (s *ScopedVault) Name() string {
+	return s.parent.Name()
+}
+
+// Capabilities returns the parent provider's capabilities.
+func (s *ScopedVault) Capabilities() vault.Capabilities {
+	return s.parent.Capabilities()
+}
+
+// Close marks the scope as closed. The parent provider stays open.
+func (s *ScopedVault) Close() error {
+	s.closed.Store(true)
+	return nil
+}
+
+// check returns an error if the scope is closed or its prefix is invalid.
+func (s *ScopedVault) check(op, path string) error {
+	if s.closed.Load() {
+		return vault.NewVaultError(op, path, s.Name(), vault.ErrClosed)
+	}
+	if s.err != nil {
+		return vault.NewVaultError(op, path, s.Name(), s.err)
+	}
+	return nil
+}
+
+// resolve returns the full path of a path within the scope.
+func (s *ScopedVault) resolve(op, path string) (string, error) {
+	if err := s.check(op, path); err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "", vault.NewVaultError(op, path, s.Name(), fmt.Errorf("%w: empty path", vault.ErrInvalidPath))
+	}
+	if err := validateScopedPath(path); err != nil {
+		return "", vault.NewVaultError(op, path, s.Name(), err)
+	}
+	return s.prefix + path, nil
+}
+
+// resolveAll resolves every path in paths.
+func (s *ScopedVault) resolveAll(op string, paths []string) ([]string, error) {
+	if err := s.check(op, ""); err != nil {
+		return nil, err
+	}
+	full := make([]string, len(paths))
+	for i, path := range paths {
+		resolved, err := s.resolve(op, path)
+		if err != nil {
+			return nil, err
+		}
+		full[i] = resolved
+	}
+	return full, nil
+}
+
+// unscope rewrites the paths in errors from the parent to be relative to
+// the scope, so callers don't see paths they didn't pass in.
+func (s *ScopedVault) unscope(err error) error {
+	if err == nil {
+		return nil
+	}
+	if vaultErr, ok := err.(*vault.VaultError); ok {
+		return s.unscopeVaultError(vaultErr)
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return err
+	}
+	errs := joined.Unwrap()
+	scoped := make([]error, len(errs))
+	for i, e := range errs {
+		scoped[i] = e
+		if vaultErr, ok := e.(*vault.VaultError); ok {
+			scoped[i] = s.unscopeVaultError(vaultErr)
+		}
+	}
+	return errors.Join(scoped...)
+}
+
+// unscopeVaultError returns a copy of err with a path relative to the scope.
+func (s *ScopedVault) unscopeVaultError(err *vault.VaultError) error {
+	if !strings.HasPrefix(err.Path, s.prefix) {
+		return err
+	}
+	scoped := *err
+	scoped.Path = strings.TrimPrefix(err.Path, s.prefix)
+	return &scoped
+}
+
+// scopePrefix validates prefix and joins it to base.
+func scopePrefix(base, prefix string) (string, error) {
+	if err := validateScopedPath(prefix); err != nil {
+		return base, err
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return base + prefix, nil
+}
+
+// scopePrefix returns the canonical prefix of a scope for prefix within
+// base, so that it matches the canonical paths the provider returns.
+func (p *Provider) scopePrefix(base, prefix string) (string, error) {
+	full, err := scopePrefix(base, prefix)
+	if err != nil {
+		return base, err
+	}
+	canonical, err := p.config.PathRules.canonicalPrefix(full)
+	if err != nil {
+		return base, err
+	}
+	return canonical, nil
+}
+
+// validateScopedPath rejects paths that could escape a scope: absolute
+// paths and paths with "." or ".." segments.
+func validateScopedPath(path string) error {
+	if strings.HasPrefix(path, "/") {
+		return fmt.Errorf("%w: %q is absolute", vault.ErrInvalidPath, path)
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "." || segment == ".." {
+			return fmt.Errorf("%w: %q escapes its scope", vault.ErrInvalidPath, path)
+		}
+	}
+	return nil
+}
+
+// Ensure ScopedVault implements vault.Vault and vault.BatchVault.
+var (
+	_ vault.Vault      = (*ScopedVault)(nil)
+	_ vault.BatchVault = (*ScopedVault)(nil)
+)
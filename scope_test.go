@@ -0,0 +1,152 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+func TestProvider_WithPrefix(t *testing.T) {
+	ctx := context.Background()
+	useBackend(t, newMemoryBackend())
+
+	p := New(Config{ServiceName: "test-scope"})
+	defer p.Close()
+
+	payments := p.WithPrefix("payments/")
+	if err := payments.Set(ctx, "stripe/key", &vault.Secret{Value: "sk"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	_ = p.Set(ctx, "shipping/key", &vault.Secret{Value: "other"})
+
+	// The parent sees the full path
+	secret, err := p.Get(ctx, "payments/stripe/key")
+	if err != nil || secret.Value != "sk" {
+		t.Errorf("expected secret under the prefix, got %v, %v", secret, err)
+	}
+
+	secret, err = payments.Get(ctx, "stripe/key")
+	if err != nil || secret.Value != "sk" {
+		t.Errorf("expected scoped Get to succeed, got %v, %v", secret, err)
+	}
+	if exists, _ := payments.Exists(ctx, "shipping/key"); exists {
+		t.Error("expected secrets outside the scope to be invisible")
+	}
+
+	list, err := payments.List(ctx, "")
+	if err != nil || len(list) != 1 || list[0] != "stripe/key" {
+		t.Errorf("expected relative paths from List, got %v, %v", list, err)
+	}
+
+	if err := payments.Delete(ctx, "stripe/key"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if list, _ := p.List(ctx, ""); len(list) != 1 || list[0] != "shipping/key" {
+		t.Errorf("expected the shared index to be updated, got %v", list)
+	}
+}
+
+func TestScopedVault_RejectsEscapes(t *testing.T) {
+	ctx := context.Background()
+	p := New(Config{ServiceName: "test-scope-escape"})
+	defer p.Close()
+
+	s := p.WithPrefix("payments")
+	if s.Prefix() != "payments/" {
+		t.Errorf("expected a trailing slash to be added, got %q", s.Prefix())
+	}
+
+	for _, path := range []string{"../shipping/key", "a/../../b", "./key", "/etc/passwd", ""} {
+		if err := s.Set(ctx, path, &vault.Secret{Value: "x"}); !errors.Is(err, vault.ErrInvalidPath) {
+			t.Errorf("Set(%q): expected ErrInvalidPath, got %v", path, err)
+		}
+	}
+	if _, err := s.List(ctx, "../"); !errors.Is(err, vault.ErrInvalidPath) {
+		t.Errorf("expected List to reject escaping prefixes, got %v", err)
+	}
+
+	bad := p.WithPrefix("../other")
+	if _, err := bad.Get(ctx, "key"); !errors.Is(err, vault.ErrInvalidPath) {
+		t.Errorf("expected an invalid prefix to fail every operation, got %v", err)
+	}
+}
+
+func TestScopedVault_Nested(t *testing.T) {
+	ctx := context.Background()
+	useBackend(t, newMemoryBackend())
+
+	p := New(Config{ServiceName: "test-scope-nested"})
+	defer p.Close()
+
+	s := p.WithPrefix("team/").WithPrefix("payments")
+	if s.Prefix() != "team/payments/" {
+		t.Fatalf("unexpected prefix %q", s.Prefix())
+	}
+
+	err := s.SetBatch(ctx, map[string]*vault.Secret{
+		"a": {Value: "1"},
+		"b": {Value: "2"},
+	})
+	if err != nil {
+		t.Fatalf("SetBatch failed: %v", err)
+	}
+	secrets, err := s.GetBatch(ctx, []string{"a", "b", "missing"})
+	if err != nil || len(secrets) != 2 || secrets["a"].Value != "1" {
+		t.Errorf("expected scoped batch results, got %v, %v", secrets, err)
+	}
+
+	list, _ := p.List(ctx, "")
+	sort.Strings(list)
+	if len(list) != 2 || list[0] != "team/payments/a" {
+		t.Errorf("expected full paths in the parent, got %v", list)
+	}
+}
+
+func TestScopedVault_CanonicalPrefix(t *testing.T) {
+	ctx := context.Background()
+	useBackend(t, newMemoryBackend())
+
+	p := New(Config{ServiceName: "test-scope-canonical"})
+	defer p.Close()
+
+	s := p.WithPrefix("pay//ments").WithPrefix("cafe\u0301")
+	if s.Prefix() != "pay/ments/caf\u00e9/" {
+		t.Fatalf("unexpected prefix %q", s.Prefix())
+	}
+	if err := s.Set(ctx, "x", &vault.Secret{Value: "1"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	_ = p.Set(ctx, "pay/other", &vault.Secret{Value: "2"})
+
+	if list, err := s.List(ctx, ""); err != nil || len(list) != 1 || list[0] != "x" {
+		t.Errorf("expected relative paths from List, got %v, %v", list, err)
+	}
+	var vaultErr *vault.VaultError
+	if _, err := s.Get(ctx, "missing"); !errors.As(err, &vaultErr) || vaultErr.Path != "missing" {
+		t.Errorf("expected error with a relative path, got %v", err)
+	}
+}
+
+func TestScopedVault_Errors(t *testing.T) {
+	ctx := context.Background()
+	p := New(Config{ServiceName: "test-scope-errors"})
+	defer p.Close()
+
+	s := p.WithPrefix("payments/")
+	_, err := s.Get(ctx, "missing")
+	var vaultErr *vault.VaultError
+	if !errors.As(err, &vaultErr) || vaultErr.Path != "missing" || !errors.Is(err, vault.ErrSecretNotFound) {
+		t.Errorf("expected ErrSecretNotFound with a scoped path, got %v", err)
+	}
+
+	_ = s.Close()
+	if _, err := s.Get(ctx, "key"); !errors.Is(err, vault.ErrClosed) {
+		t.Errorf("expected ErrClosed after closing the scope, got %v", err)
+	}
+	if _, err := p.List(ctx, ""); err != nil {
+		t.Errorf("expected the parent to stay open, got %v", err)
+	}
+}
@@ -0,0 +1,301 @@
+//go:build linux
+
+package keyring
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	dbus "github.com/godbus/dbus/v5"
+	zkeyring "github.com/zalando/go-keyring"
+)
+
+// newOSBackend returns go-keyring for the default collection and a direct
+// Secret Service client when Config.Collection selects another one.
+func newOSBackend(config Config) backend {
+	if config.Collection == "" {
+		return osBackend{}
+	}
+	label := config.CollectionLabel
+	if label == "" {
+		label = config.Collection
+	}
+	return &secretServiceBackend{name: config.Collection, label: label}
+}
+
+// secretServiceBackend stores secrets in a chosen Secret Service collection.
+// Items carry the same "service" and "username" attributes as go-keyring's,
+// so other tools see them the same way.
+type secretServiceBackend struct {
+	name  string // collection alias, name or label
+	label string // label for a created collection
+
+	mu   sync.Mutex
+	path dbus.ObjectPath // resolved collection, cached
+}
+
+func (b *secretServiceBackend) Get(service, user string) (string, error) {
+	ctx := context.Background()
+	conn, collection, err := b.open(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	item, err := b.findItem(ctx, conn, collection, service, user)
+	if err != nil {
+		return "", err
+	}
+
+	session, err := openSession(ctx, conn)
+	if err != nil {
+		return "", err
+	}
+	defer closeSession(conn, session)
+
+	var secret secretServiceSecret
+	err = conn.Object(secretServiceName, item).CallWithContext(ctx, secretItemIface+".GetSecret", 0, session).
+		Store(&secret)
+	if err != nil {
+		return "", b.check(err)
+	}
+	value := string(secret.Value)
+	clear(secret.Value)
+	return value, nil
+}
+
+func (b *secretServiceBackend) Set(service, user, value string) error {
+	ctx := context.Background()
+	conn, collection, err := b.open(ctx)
+	if err != nil {
+		return err
+	}
+
+	session, err := openSession(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer closeSession(conn, session)
+
+	properties := map[string]dbus.Variant{
+		secretItemIface + ".Label":      dbus.MakeVariant(fmt.Sprintf("Password for '%s' on '%s'", user, service)),
+		secretItemIface + ".Attributes": dbus.MakeVariant(itemAttributes(service, user)),
+	}
+	secret := secretServiceSecret{
+		Session:     session,
+		Parameters:  []byte{},
+		Value:       []byte(value),
+		ContentType: "text/plain; charset=utf8",
+	}
+	defer clear(secret.Value)
+
+	var item, prompt dbus.ObjectPath
+	err = conn.Object(secretServiceName, collection).CallWithContext(ctx,
+		secretCollectionIface+".CreateItem", 0, properties, secret, true).
+		Store(&item, &prompt)
+	if err != nil {
+		return b.check(err)
+	}
+	if prompt != "/" {
+		_, err = runPrompt(ctx, conn, prompt, "")
+	}
+	return err
+}
+
+func (b *secretServiceBackend) Delete(service, user string) error {
+	ctx := context.Background()
+	conn, collection, err := b.open(ctx)
+	if err != nil {
+		return err
+	}
+
+	item, err := b.findItem(ctx, conn, collection, service, user)
+	if err != nil {
+		return err
+	}
+
+	var prompt dbus.ObjectPath
+	err = conn.Object(secretServiceName, item).CallWithContext(ctx, secretItemIface+".Delete", 0).Store(&prompt)
+	if err != nil {
+		return b.check(err)
+	}
+	if prompt != "/" {
+		_, err = runPrompt(ctx, conn, prompt, "")
+	}
+	return err
+}
+
+func (b *secretServiceBackend) unlock(ctx context.Context, opts UnlockOptions) error {
+	return unlockCollection(ctx, b.collection, opts)
+}
+
+func (b *secretServiceBackend) lock(ctx context.Context) error {
+	return lockCollection(ctx, b.collection)
+}
+
+func (b *secretServiceBackend) subscribe(ctx context.Context, service string, emit func(user string, typ EventType)) (<-chan struct{}, error) {
+	return osBackend{}.subscribe(ctx, service, emit)
+}
+
+// open connects to the session bus and returns the unlocked collection.
+// Like go-keyring, it shows the unlock prompt if the collection is locked.
+func (b *secretServiceBackend) open(ctx context.Context) (*dbus.Conn, dbus.ObjectPath, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, "", err
+	}
+	collection, err := b.collection(ctx, conn)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	err = conn.Object(secretServiceName, secretServicePath).CallWithContext(ctx,
+		secretServiceIface+".Unlock", 0, []dbus.ObjectPath{collection}).
+		Store(&unlocked, &prompt)
+	if err != nil {
+		return nil, "", b.check(err)
+	}
+	if prompt != "/" {
+		if _, err := runPrompt(ctx, conn, prompt, ""); err != nil {
+			return nil, "", err
+		}
+	}
+	return conn, collection, nil
+}
+
+// collection resolves the configured collection, creating it if needed.
+// The session collection is never created; it exists while the user is
+// logged in.
+func (b *secretServiceBackend) collection(ctx context.Context, conn *dbus.Conn) (dbus.ObjectPath, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.path != "" {
+		return b.path, nil
+	}
+
+	path, err := findCollection(ctx, conn, b.name)
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		if b.name == SessionCollection {
+			return "", fmt.Errorf("%w: no session collection", ErrDaemonUnavailable)
+		}
+		if path, err = createCollection(ctx, conn, b.label); err != nil {
+			return "", err
+		}
+	}
+	b.path = path
+	return path, nil
+}
+
+// check forgets the cached collection if err says it no longer exists, so
+// it is looked up, or created, again on the next call.
+func (b *secretServiceBackend) check(err error) error {
+	if name, ok := dbusErrorName(err); ok && name == "org.freedesktop.DBus.Error.UnknownObject" {
+		b.mu.Lock()
+		b.path = ""
+		b.mu.Unlock()
+	}
+	return err
+}
+
+// findItem returns the item storing user under service.
+func (b *secretServiceBackend) findItem(ctx context.Context, conn *dbus.Conn, collection dbus.ObjectPath, service, user string) (dbus.ObjectPath, error) {
+	var items []dbus.ObjectPath
+	err := conn.Object(secretServiceName, collection).CallWithContext(ctx,
+		secretCollectionIface+".SearchItems", 0, itemAttributes(service, user)).
+		Store(&items)
+	if err != nil {
+		return "", b.check(err)
+	}
+	if len(items) == 0 {
+		return "", zkeyring.ErrNotFound
+	}
+	return items[0], nil
+}
+
+// findCollection returns the collection with the given alias, object name
+// or label, or "" if there is none.
+func findCollection(ctx context.Context, conn *dbus.Conn, name string) (dbus.ObjectPath, error) {
+	service := conn.Object(secretServiceName, secretServicePath)
+
+	var alias dbus.ObjectPath
+	if err := service.CallWithContext(ctx, secretServiceIface+".ReadAlias", 0, name).Store(&alias); err != nil {
+		return "", err
+	}
+	if alias != "/" {
+		return alias, nil
+	}
+
+	v, err := service.GetProperty(secretServiceIface + ".Collections")
+	if err != nil {
+		return "", err
+	}
+	var paths []dbus.ObjectPath
+	if err := v.Store(&paths); err != nil {
+		return "", err
+	}
+	for _, path := range paths {
+		if path == dbus.ObjectPath(secretCollectionPrefix+name) {
+			return path, nil
+		}
+	}
+	for _, path := range paths {
+		label, err := conn.Object(secretServiceName, path).GetProperty(secretCollectionIface + ".Label")
+		if err == nil && label.Value() == name {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
+// createCollection creates a collection with the given label. The Secret
+// Service usually prompts the user for the new collection's password.
+func createCollection(ctx context.Context, conn *dbus.Conn, label string) (dbus.ObjectPath, error) {
+	properties := map[string]dbus.Variant{
+		secretCollectionIface + ".Label": dbus.MakeVariant(label),
+	}
+
+	var collection, prompt dbus.ObjectPath
+	err := conn.Object(secretServiceName, secretServicePath).CallWithContext(ctx,
+		secretServiceIface+".CreateCollection", 0, properties, "").
+		Store(&collection, &prompt)
+	if err != nil {
+		return "", err
+	}
+	if prompt == "/" {
+		return collection, nil
+	}
+
+	result, err := runPrompt(ctx, conn, prompt, "")
+	if err != nil {
+		return "", err
+	}
+	if err := result.Store(&collection); err != nil {
+		return "", fmt.Errorf("create collection %q: %w", label, err)
+	}
+	return collection, nil
+}
+
+// openSession opens a plain Secret Service session for transferring secrets.
+func openSession(ctx context.Context, conn *dbus.Conn) (dbus.ObjectPath, error) {
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	err := conn.Object(secretServiceName, secretServicePath).CallWithContext(ctx,
+		secretServiceIface+".OpenSession", 0, "plain", dbus.MakeVariant("")).
+		Store(&output, &session)
+	return session, err
+}
+
+// closeSession closes a session opened by openSession.
+func closeSession(conn *dbus.Conn, session dbus.ObjectPath) {
+	_ = conn.Object(secretServiceName, session).Call(secretSessionIface+".Close", 0).Err
+}
+
+// itemAttributes returns the lookup attributes go-keyring uses for an item.
+func itemAttributes(service, user string) map[string]string {
+	return map[string]string{"username": user, "service": service}
+}
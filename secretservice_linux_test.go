@@ -0,0 +1,33 @@
+//go:build linux
+
+package keyring
+
+import "testing"
+
+func TestNewOSBackend_Collection(t *testing.T) {
+	if _, ok := newOSBackend(Config{}).(osBackend); !ok {
+		t.Error("expected go-keyring for the default collection")
+	}
+
+	tests := []struct {
+		config Config
+		label  string
+	}{
+		{Config{Collection: "myapp"}, "myapp"},
+		{Config{Collection: "myapp", CollectionLabel: "My App"}, "My App"},
+		{Config{Collection: SessionCollection}, SessionCollection},
+	}
+	for _, tt := range tests {
+		b, ok := newOSBackend(tt.config).(*secretServiceBackend)
+		if !ok {
+			t.Fatalf("expected a Secret Service backend for %q", tt.config.Collection)
+		}
+		if b.name != tt.config.Collection || b.label != tt.label {
+			t.Errorf("expected collection %q labeled %q, got %q labeled %q",
+				tt.config.Collection, tt.label, b.name, b.label)
+		}
+
+		var _ locker = b
+		var _ eventSource = b
+	}
+}
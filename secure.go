@@ -0,0 +1,457 @@
+package keyring
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+	"unsafe"
+
+	"github.com/agentplexus/omnivault/vault"
+	zkeyring "github.com/zalando/go-keyring"
+)
+
+// SecureSecret is a secret whose values are held in locked, zeroizable memory.
+//
+// Unlike vault.Secret, whose string fields can never be wiped, a SecureSecret
+// keeps its value and fields in buffers that are excluded from swap where the
+// platform allows it and are overwritten with zeros by Destroy. Slices
+// returned by Value and Field alias those buffers and must not be used after
+// Destroy has been called.
+//
+// The buffers live outside the Go heap and are only released by Destroy,
+// never by the garbage collector, so slices stay valid after the
+// SecureSecret itself becomes unreachable. A SecureSecret that is never
+// destroyed keeps its memory, and its secret, until the process exits.
+type SecureSecret struct {
+	// Metadata contains additional information about the secret.
+	Metadata vault.Metadata
+
+	mu        sync.Mutex
+	value     *lockedBuffer
+	fields    map[string]*lockedBuffer
+	destroyed bool
+}
+
+// Value returns the primary secret value.
+// The returned slice is only valid until Destroy is called.
+func (s *SecureSecret) Value() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.destroyed || s.value == nil {
+		return nil
+	}
+	return s.value.bytes()
+}
+
+// Field returns a named field value, or nil if the field doesn't exist.
+// An empty name or "value" returns the primary value.
+// The returned slice is only valid until Destroy is called.
+func (s *SecureSecret) Field(name string) []byte {
+	if name == "" || name == "value" {
+		return s.Value()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.destroyed {
+		return nil
+	}
+	if b, ok := s.fields[name]; ok {
+		return b.bytes()
+	}
+	return nil
+}
+
+// FieldNames returns the names of all additional fields in sorted order.
+func (s *SecureSecret) FieldNames() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.fields))
+	for name := range s.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Locked reports whether every buffer backing the secret is locked in memory.
+// Locking can fail when the process exceeds RLIMIT_MEMLOCK or on platforms
+// without mlock support; the buffers are still zeroed by Destroy.
+func (s *SecureSecret) Locked() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.value != nil && !s.value.locked {
+		return false
+	}
+	for _, b := range s.fields {
+		if !b.locked {
+			return false
+		}
+	}
+	return true
+}
+
+// Destroy zeroes and releases all buffers held by the secret.
+// It is safe to call Destroy more than once.
+func (s *SecureSecret) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.destroyed {
+		return
+	}
+	s.destroyed = true
+	if s.value != nil {
+		s.value.destroy()
+		s.value = nil
+	}
+	for name, b := range s.fields {
+		b.destroy()
+		delete(s.fields, name)
+	}
+}
+
+// Destroyed reports whether Destroy has been called.
+func (s *SecureSecret) Destroyed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.destroyed
+}
+
+// GetSecure retrieves a secret from the OS keyring into locked, zeroizable memory.
+// The caller must call Destroy on the returned secret once it is no longer needed.
+//
+// The value is copied straight from the backend result into locked buffers
+// without intermediate string conversions. The backend result itself is
+// produced by the OS keyring library and cannot be wiped.
+func (p *Provider) GetSecure(ctx context.Context, path string) (*SecureSecret, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return nil, vault.NewVaultError("GetSecure", path, p.Name(), vault.ErrClosed)
+	}
+	path, err := p.canonical("GetSecure", path)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := p.handle(ctx, &Request{Op: "GetSecure", Path: path}, func(ctx context.Context, req *Request) (*Response, error) {
+		secure, err := p.getSecure(ctx, path)
+		return &Response{Secure: secure}, err
+	})
+	return resp.Secure, err
+}
+
+// getSecure reads and decodes a secret into locked memory. The caller must
+// hold p.mu.
+func (p *Provider) getSecure(ctx context.Context, path string) (*SecureSecret, error) {
+	value, release, err := p.secureRead(ctx, path)
+	defer release()
+	if err != nil {
+		if errors.Is(err, zkeyring.ErrNotFound) {
+			if p.fallback != nil {
+				p.logFallback(ctx, path)
+				return p.fallback.GetSecure(ctx, path)
+			}
+			return nil, vault.NewVaultError("GetSecure", path, p.Name(), vault.ErrSecretNotFound)
+		}
+		return nil, vault.NewVaultError("GetSecure", path, p.Name(), err)
+	}
+
+	etag := p.readETag(ctx, path, value)
+
+	secret := &SecureSecret{}
+	if !p.config.JSONFormat || !decodeSecureJSON(value, secret) {
+		// Plain format, or fall back to plain value if JSON parsing fails
+		if p.config.JSONFormat {
+			p.log.DebugContext(ctx, "keyring: stored value is not JSON, returning it as a plain value", p.logPath(path))
+		}
+		secret.value = newLockedBuffer(len(value))
+		copy(secret.value.data, value)
+	}
+	secret.Metadata.Provider = p.Name()
+	secret.Metadata.Path = path
+	if etag != "" {
+		if secret.Metadata.Extra == nil {
+			secret.Metadata.Extra = make(map[string]any, 1)
+		}
+		secret.Metadata.Extra[MetadataETag] = etag
+	}
+
+	return secret, nil
+}
+
+// secureRead is cachedRead for getSecure. A hit in a Secure cache is copied
+// straight into a new locked buffer rather than a heap string; value is a
+// view of that buffer, valid until release is called.
+func (p *Provider) secureRead(ctx context.Context, path string) (value string, release func(), err error) {
+	if p.cache != nil && p.cache.config.Secure {
+		if buf, found, hit := p.cache.getLocked(path); hit {
+			if !found {
+				return "", func() {}, zkeyring.ErrNotFound
+			}
+			return bufferString(buf), buf.destroy, nil
+		}
+	}
+	value, err = p.cachedRead(ctx, path)
+	return value, func() {}, err
+}
+
+// bufferString returns a string sharing the memory of b, which must not be
+// modified or destroyed while the string is in use.
+func bufferString(b *lockedBuffer) string {
+	if len(b.data) == 0 {
+		return ""
+	}
+	return unsafe.String(&b.data[0], len(b.data))
+}
+
+// stringBytes returns the bytes of s without copying them. The result must
+// not be modified.
+func stringBytes(s string) []byte {
+	return unsafe.Slice(unsafe.StringData(s), len(s))
+}
+
+// secureEnvelope mirrors the JSON layout of vault.Secret, decoding secret
+// material directly into locked buffers.
+type secureEnvelope struct {
+	Value      *lockedString            `json:"value,omitempty"`
+	ValueBytes *lockedBase64            `json:"valueBytes,omitempty"`
+	Fields     map[string]*lockedString `json:"fields,omitempty"`
+	Metadata   vault.Metadata           `json:"metadata,omitempty"`
+}
+
+// destroy releases every buffer allocated while decoding the envelope.
+func (e *secureEnvelope) destroy() {
+	if e.Value != nil && e.Value.buf != nil {
+		e.Value.buf.destroy()
+	}
+	if e.ValueBytes != nil && e.ValueBytes.buf != nil {
+		e.ValueBytes.buf.destroy()
+	}
+	for _, f := range e.Fields {
+		if f != nil && f.buf != nil {
+			f.buf.destroy()
+		}
+	}
+}
+
+// decodeSecureJSON decodes a JSON-format secret into s.
+// It reports false, leaving s untouched, if value is not a JSON secret.
+func decodeSecureJSON(value string, s *SecureSecret) bool {
+	if value == "" {
+		return false
+	}
+	// Parse the backend string in place rather than copying it into a []byte.
+	// The JSON decoder never writes to its input.
+	raw := stringBytes(value)
+
+	var env secureEnvelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		env.destroy()
+		return false
+	}
+
+	// ValueBytes takes precedence over Value, matching vault.Secret.String.
+	switch {
+	case env.ValueBytes != nil && env.ValueBytes.buf != nil && len(env.ValueBytes.buf.data) > 0:
+		s.value = env.ValueBytes.buf
+		if env.Value != nil && env.Value.buf != nil {
+			env.Value.buf.destroy()
+		}
+	case env.Value != nil && env.Value.buf != nil:
+		s.value = env.Value.buf
+		if env.ValueBytes != nil && env.ValueBytes.buf != nil {
+			env.ValueBytes.buf.destroy()
+		}
+	default:
+		s.value = newLockedBuffer(0)
+	}
+	if len(env.Fields) > 0 {
+		s.fields = make(map[string]*lockedBuffer, len(env.Fields))
+		for name, f := range env.Fields {
+			if f != nil && f.buf != nil {
+				s.fields[name] = f.buf
+			}
+		}
+	}
+	s.Metadata = env.Metadata
+	return true
+}
+
+// lockedString decodes a JSON string into a locked buffer.
+type lockedString struct {
+	buf *lockedBuffer
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (l *lockedString) UnmarshalJSON(data []byte) error {
+	if len(data) < 2 || data[0] != '"' || data[len(data)-1] != '"' {
+		return errors.New("keyring: expected JSON string")
+	}
+	buf := newLockedBuffer(len(data) - 2)
+	n, ok := unquoteJSONInto(buf.data, data[1:len(data)-1])
+	if !ok {
+		buf.destroy()
+		return errors.New("keyring: invalid JSON string")
+	}
+	buf.truncate(n)
+	l.buf = buf
+	return nil
+}
+
+// lockedBase64 decodes a base64-encoded JSON string into a locked buffer.
+type lockedBase64 struct {
+	buf *lockedBuffer
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (l *lockedBase64) UnmarshalJSON(data []byte) error {
+	if len(data) < 2 || data[0] != '"' || data[len(data)-1] != '"' {
+		return errors.New("keyring: expected JSON string")
+	}
+	src := data[1 : len(data)-1]
+	buf := newLockedBuffer(base64.StdEncoding.DecodedLen(len(src)))
+	n, err := base64.StdEncoding.Decode(buf.data, src)
+	if err != nil {
+		buf.destroy()
+		return err
+	}
+	buf.truncate(n)
+	l.buf = buf
+	return nil
+}
+
+// unquoteJSONInto decodes the body of a JSON string literal (without the
+// surrounding quotes) into dst, returning the number of bytes written.
+// dst must be at least len(src) bytes long; unescaping never grows the input.
+func unquoteJSONInto(dst, src []byte) (int, bool) {
+	n := 0
+	for i := 0; i < len(src); {
+		c := src[i]
+		if c != '\\' {
+			dst[n] = c
+			n++
+			i++
+			continue
+		}
+		if i+1 >= len(src) {
+			return 0, false
+		}
+		switch src[i+1] {
+		case '"', '\\', '/':
+			dst[n] = src[i+1]
+		case 'b':
+			dst[n] = '\b'
+		case 'f':
+			dst[n] = '\f'
+		case 'n':
+			dst[n] = '\n'
+		case 'r':
+			dst[n] = '\r'
+		case 't':
+			dst[n] = '\t'
+		case 'u':
+			r, size := decodeJSONEscape(src[i:])
+			if size == 0 {
+				return 0, false
+			}
+			n += utf8.EncodeRune(dst[n:], r)
+			i += size
+			continue
+		default:
+			return 0, false
+		}
+		n++
+		i += 2
+	}
+	return n, true
+}
+
+// decodeJSONEscape decodes a \uXXXX escape, including surrogate pairs, at the
+// start of src. It returns the rune and the number of input bytes consumed,
+// or a size of zero if the escape is malformed.
+func decodeJSONEscape(src []byte) (rune, int) {
+	r1, ok := hex4(src)
+	if !ok {
+		return 0, 0
+	}
+	if !utf16.IsSurrogate(r1) {
+		return r1, 6
+	}
+	if r2, ok := hex4(src[6:]); ok {
+		if r := utf16.DecodeRune(r1, r2); r != utf8.RuneError {
+			return r, 12
+		}
+	}
+	return utf8.RuneError, 6
+}
+
+// hex4 parses a \uXXXX escape at the start of src.
+func hex4(src []byte) (rune, bool) {
+	if len(src) < 6 || src[0] != '\\' || src[1] != 'u' {
+		return 0, false
+	}
+	var r rune
+	for _, c := range src[2:6] {
+		switch {
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c = c - 'a' + 10
+		case 'A' <= c && c <= 'F':
+			c = c - 'A' + 10
+		default:
+			return 0, false
+		}
+		r = r*16 + rune(c)
+	}
+	return r, true
+}
+
+// lockedBuffer is a byte buffer that is locked in memory where supported and
+// zeroed when destroyed.
+type lockedBuffer struct {
+	data   []byte
+	mem    []byte // full allocation backing data
+	mapped bool
+	locked bool
+}
+
+// bytes returns the buffer contents.
+func (b *lockedBuffer) bytes() []byte {
+	return b.data
+}
+
+// truncate shrinks the visible buffer to n bytes, zeroing the remainder.
+func (b *lockedBuffer) truncate(n int) {
+	clear(b.data[n:])
+	b.data = b.data[:n]
+}
+
+// destroy zeroes the buffer and releases its memory.
+func (b *lockedBuffer) destroy() {
+	if b.mem == nil {
+		return
+	}
+	clear(b.mem)
+	freeLocked(b.mem, b.mapped, b.locked)
+	b.data = nil
+	b.mem = nil
+	b.mapped = false
+	b.locked = false
+}
+
+// newLockedBuffer allocates a zeroed buffer of the given size and attempts
+// to lock it in memory.
+func newLockedBuffer(size int) *lockedBuffer {
+	mem, mapped, locked := allocLocked(size)
+	return &lockedBuffer{data: mem[:size], mem: mem, mapped: mapped, locked: locked}
+}
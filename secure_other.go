@@ -0,0 +1,12 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package keyring
+
+// allocLocked allocates a buffer of the given size. Memory locking is not
+// supported on this platform, so the buffer is only zeroed on Destroy.
+func allocLocked(size int) (mem []byte, mapped, locked bool) {
+	return make([]byte, size), false, false
+}
+
+// freeLocked releases memory returned by allocLocked.
+func freeLocked([]byte, bool, bool) {}
@@ -0,0 +1,214 @@
+package keyring
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+func TestProvider_GetSecure(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("plain value", func(t *testing.T) {
+		p := New(Config{ServiceName: "test-secure-plain"})
+		defer p.Close()
+
+		if err := p.Set(ctx, "key", &vault.Secret{Value: "value1"}); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		defer func() { _ = p.Delete(ctx, "key") }()
+
+		secret, err := p.GetSecure(ctx, "key")
+		if err != nil {
+			t.Fatalf("GetSecure failed: %v", err)
+		}
+		defer secret.Destroy()
+
+		if string(secret.Value()) != "value1" {
+			t.Errorf("expected value %q, got %q", "value1", secret.Value())
+		}
+		if secret.Metadata.Path != "key" {
+			t.Errorf("expected path %q, got %q", "key", secret.Metadata.Path)
+		}
+	})
+
+	t.Run("JSON format", func(t *testing.T) {
+		p := New(Config{ServiceName: "test-secure-json", JSONFormat: true})
+		defer p.Close()
+
+		err := p.Set(ctx, "db", &vault.Secret{
+			Value: "pa\"ss\\wörd\n🔑",
+			Fields: map[string]string{
+				"username": "admin",
+			},
+			Metadata: vault.Metadata{Tags: map[string]string{"env": "prod"}},
+		})
+		if err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		defer func() { _ = p.Delete(ctx, "db") }()
+
+		secret, err := p.GetSecure(ctx, "db")
+		if err != nil {
+			t.Fatalf("GetSecure failed: %v", err)
+		}
+		defer secret.Destroy()
+
+		if string(secret.Value()) != "pa\"ss\\wörd\n🔑" {
+			t.Errorf("unexpected value %q", secret.Value())
+		}
+		if string(secret.Field("username")) != "admin" {
+			t.Errorf("expected username %q, got %q", "admin", secret.Field("username"))
+		}
+		if names := secret.FieldNames(); len(names) != 1 || names[0] != "username" {
+			t.Errorf("unexpected field names %v", names)
+		}
+		if secret.Metadata.Tags["env"] != "prod" {
+			t.Errorf("expected tag env=prod, got %v", secret.Metadata.Tags)
+		}
+	})
+
+	t.Run("JSON format with binary value", func(t *testing.T) {
+		p := New(Config{ServiceName: "test-secure-binary", JSONFormat: true})
+		defer p.Close()
+
+		if err := p.Set(ctx, "bin", &vault.Secret{ValueBytes: []byte{0, 1, 2, 255}}); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		defer func() { _ = p.Delete(ctx, "bin") }()
+
+		secret, err := p.GetSecure(ctx, "bin")
+		if err != nil {
+			t.Fatalf("GetSecure failed: %v", err)
+		}
+		defer secret.Destroy()
+
+		if got := secret.Value(); string(got) != "\x00\x01\x02\xff" {
+			t.Errorf("unexpected value %v", got)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		p := New(Config{ServiceName: "test-secure-notfound"})
+		defer p.Close()
+
+		_, err := p.GetSecure(ctx, "missing")
+		if !errors.Is(err, vault.ErrSecretNotFound) {
+			t.Errorf("expected ErrSecretNotFound, got %v", err)
+		}
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		p := New(Config{ServiceName: "test-secure-closed"})
+		_ = p.Close()
+
+		_, err := p.GetSecure(ctx, "key")
+		if !errors.Is(err, vault.ErrClosed) {
+			t.Errorf("expected ErrClosed, got %v", err)
+		}
+	})
+}
+
+func TestSecureSecret_Destroy(t *testing.T) {
+	ctx := context.Background()
+	p := New(Config{ServiceName: "test-secure-destroy", JSONFormat: true})
+	defer p.Close()
+
+	err := p.Set(ctx, "key", &vault.Secret{Value: "value", Fields: map[string]string{"f": "v"}})
+	if err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	defer func() { _ = p.Delete(ctx, "key") }()
+
+	secret, err := p.GetSecure(ctx, "key")
+	if err != nil {
+		t.Fatalf("GetSecure failed: %v", err)
+	}
+
+	secret.Destroy()
+	secret.Destroy() // idempotent
+
+	if !secret.Destroyed() {
+		t.Error("expected secret to be destroyed")
+	}
+	if secret.Value() != nil {
+		t.Error("expected nil value after Destroy")
+	}
+	if secret.Field("f") != nil {
+		t.Error("expected nil field after Destroy")
+	}
+}
+
+func TestSecureSecret_ValueOutlivesHandle(t *testing.T) {
+	ctx := context.Background()
+	p := New(Config{ServiceName: "test-secure-gc"})
+	defer p.Close()
+
+	if err := p.Set(ctx, "key", &vault.Secret{Value: "value"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	defer func() { _ = p.Delete(ctx, "key") }()
+
+	secret, err := p.GetSecure(ctx, "key")
+	if err != nil {
+		t.Fatalf("GetSecure failed: %v", err)
+	}
+	v := secret.Value()
+	secret = nil
+	runtime.GC()
+	runtime.GC()
+
+	if string(v) != "value" {
+		t.Errorf("value after the handle was collected = %q, want %q", v, "value")
+	}
+}
+
+func TestUnquoteJSONInto(t *testing.T) {
+	inputs := []string{
+		"",
+		"plain",
+		"quote\" backslash\\ slash/",
+		"\b\f\n\r\t",
+		"\x01\x1f",
+		"ünïcödé",
+		"emoji 🔑 and 世界",
+		"<html>&amp;",
+	}
+	for _, in := range inputs {
+		quoted, err := json.Marshal(in)
+		if err != nil {
+			t.Fatalf("marshal %q: %v", in, err)
+		}
+		dst := make([]byte, len(quoted))
+		n, ok := unquoteJSONInto(dst, quoted[1:len(quoted)-1])
+		if !ok {
+			t.Errorf("unquote %s failed", quoted)
+			continue
+		}
+		if string(dst[:n]) != in {
+			t.Errorf("unquote %s: expected %q, got %q", quoted, in, dst[:n])
+		}
+	}
+
+	t.Run("surrogate pair", func(t *testing.T) {
+		src := []byte(`\ud83d\udd11`)
+		dst := make([]byte, len(src))
+		n, ok := unquoteJSONInto(dst, src)
+		if !ok || string(dst[:n]) != "🔑" {
+			t.Errorf("expected %q, got %q (ok=%v)", "🔑", dst[:n], ok)
+		}
+	})
+
+	t.Run("invalid escape", func(t *testing.T) {
+		for _, src := range []string{`\x`, `\`, `\u12`, `\uZZZZ`} {
+			dst := make([]byte, len(src))
+			if _, ok := unquoteJSONInto(dst, []byte(src)); ok {
+				t.Errorf("expected %q to be rejected", src)
+			}
+		}
+	})
+}
@@ -0,0 +1,35 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package keyring
+
+import "golang.org/x/sys/unix"
+
+// allocLocked maps anonymous memory for a buffer of the given size and locks
+// it so it is never written to swap. Each buffer gets its own mapping, which
+// keeps secret material off the Go heap and ensures unlocking one buffer
+// can't unlock pages shared with another.
+func allocLocked(size int) (mem []byte, mapped, locked bool) {
+	if size == 0 {
+		return []byte{}, false, true
+	}
+	mem, err := unix.Mmap(-1, 0, size, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_ANON|unix.MAP_PRIVATE)
+	if err != nil {
+		return make([]byte, size), false, false
+	}
+	if err := unix.Mlock(mem); err != nil {
+		// Usually RLIMIT_MEMLOCK; keep the mapping so Destroy still zeroes it.
+		return mem, true, false
+	}
+	return mem, true, true
+}
+
+// freeLocked unlocks and unmaps memory returned by allocLocked.
+// The caller zeroes the memory beforehand.
+func freeLocked(mem []byte, mapped, locked bool) {
+	if locked && len(mem) > 0 {
+		_ = unix.Munlock(mem)
+	}
+	if mapped {
+		_ = unix.Munmap(mem)
+	}
+}
@@ -0,0 +1,236 @@
+package keyring
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/agentplexus/omnivault/vault"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+)
+
+// instrumentationName identifies the package's tracer and meter.
+const instrumentationName = "github.com/agentplexus/omnivault-keyring"
+
+// Telemetry attribute keys.
+const (
+	attrOperation   = attribute.Key("keyring.operation")
+	attrPath        = attribute.Key("keyring.path")
+	attrTarget      = attribute.Key("keyring.target")
+	attrPathCount   = attribute.Key("keyring.path_count")
+	attrBackend     = attribute.Key("keyring.backend")
+	attrProfile     = attribute.Key("keyring.profile")
+	attrOutcome     = attribute.Key("keyring.outcome")
+	attrPayloadSize = attribute.Key("keyring.payload_size")
+	attrIndexSize   = attribute.Key("keyring.index_size")
+	attrCacheResult = attribute.Key("keyring.cache.result")
+)
+
+// telemetry records spans and metrics for a provider's operations when
+// Config.TracerProvider or Config.MeterProvider is set.
+type telemetry struct {
+	p      *Provider
+	tracer trace.Tracer
+	attrs  []attribute.KeyValue // identify the provider on every span and metric
+
+	duration  metric.Float64Histogram
+	errors    metric.Int64Counter
+	indexSize metric.Int64Gauge
+
+	cacheRequests  metric.Int64ObservableCounter
+	cacheEvictions metric.Int64ObservableCounter
+	cacheCallback  metric.Registration // nil unless the cache is observed
+}
+
+// newTelemetry returns the telemetry of p, or nil if neither provider is
+// configured. Instruments that can't be created are replaced by no-ops.
+func newTelemetry(p *Provider) *telemetry {
+	tp, mp := p.config.TracerProvider, p.config.MeterProvider
+	if tp == nil && mp == nil {
+		return nil
+	}
+	if tp == nil {
+		tp = tracenoop.NewTracerProvider()
+	}
+	if mp == nil {
+		mp = metricnoop.NewMeterProvider()
+	}
+
+	t := &telemetry{
+		p:      p,
+		tracer: tp.Tracer(instrumentationName),
+		attrs:  []attribute.KeyValue{attrBackend.String(p.Backend()), attrProfile.String(p.profile)},
+	}
+	meter := mp.Meter(instrumentationName)
+	noop := metricnoop.Meter{}
+
+	var err error
+	if t.duration, err = meter.Float64Histogram("keyring.operation.duration",
+		metric.WithDescription("Duration of keyring operations."), metric.WithUnit("s")); err != nil {
+		t.duration, _ = noop.Float64Histogram("")
+	}
+	if t.errors, err = meter.Int64Counter("keyring.operation.errors",
+		metric.WithDescription("Failed keyring operations, by error category."), metric.WithUnit("{error}")); err != nil {
+		t.errors, _ = noop.Int64Counter("")
+	}
+	if t.indexSize, err = meter.Int64Gauge("keyring.index.size",
+		metric.WithDescription("Number of paths in the index."), metric.WithUnit("{secret}")); err != nil {
+		t.indexSize, _ = noop.Int64Gauge("")
+	}
+
+	if p.cache != nil {
+		var err1, err2 error
+		t.cacheRequests, err1 = meter.Int64ObservableCounter("keyring.cache.requests",
+			metric.WithDescription("Reads looked up in the cache, by result."), metric.WithUnit("{request}"))
+		t.cacheEvictions, err2 = meter.Int64ObservableCounter("keyring.cache.evictions",
+			metric.WithDescription("Entries evicted to keep the cache within its size limit."), metric.WithUnit("{entry}"))
+		if errors.Join(err1, err2) == nil {
+			t.cacheCallback, _ = meter.RegisterCallback(t.observeCache, t.cacheRequests, t.cacheEvictions)
+		}
+	}
+	return t
+}
+
+// close stops observing the cache.
+func (t *telemetry) close() {
+	if t.cacheCallback != nil {
+		_ = t.cacheCallback.Unregister()
+	}
+}
+
+// observeCache reports the cache statistics.
+func (t *telemetry) observeCache(_ context.Context, o metric.Observer) error {
+	stats := t.p.cache.snapshot()
+	for result, n := range map[string]uint64{"hit": stats.Hits, "negative_hit": stats.NegativeHits, "miss": stats.Misses} {
+		o.ObserveInt64(t.cacheRequests, int64(n), metric.WithAttributes(append(t.attrs, attrCacheResult.String(result))...))
+	}
+	o.ObserveInt64(t.cacheEvictions, int64(stats.Evictions), metric.WithAttributes(t.attrs...))
+	return nil
+}
+
+// middleware records a span and metrics for every operation. It is the
+// innermost handler, so it measures the work of the provider itself.
+func (t *telemetry) middleware(next Handler) Handler {
+	return func(ctx context.Context, req *Request) (*Response, error) {
+		attrs := append([]attribute.KeyValue{attrOperation.String(req.Op)}, t.attrs...)
+		spanAttrs := slices.Clip(attrs)
+		if req.Paths == nil {
+			spanAttrs = append(spanAttrs, attrPath.String(t.path(req.Path)))
+		} else {
+			spanAttrs = append(spanAttrs, attrPathCount.Int(len(req.Paths)))
+		}
+		if req.Target != "" {
+			spanAttrs = append(spanAttrs, attrTarget.String(t.path(req.Target)))
+		}
+		if req.Secret != nil {
+			spanAttrs = append(spanAttrs, attrPayloadSize.Int(secretSize(req.Secret)))
+		}
+
+		start := time.Now()
+		ctx, span := t.tracer.Start(ctx, "keyring."+req.Op,
+			trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(spanAttrs...))
+		defer span.End()
+
+		resp, err := next(ctx, req)
+
+		outcome := errorCategory(err)
+		attrs = append(attrs, attrOutcome.String(outcome))
+		t.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+		span.SetAttributes(attrOutcome.String(outcome))
+		if resp != nil && resp.Secret != nil {
+			span.SetAttributes(attrPayloadSize.Int(secretSize(resp.Secret)))
+		}
+		if err != nil && outcome != "not_found" {
+			t.errors.Add(ctx, 1, metric.WithAttributes(attrs...))
+			// The error message names the path, so only its category is recorded.
+			span.SetStatus(codes.Error, outcome)
+		}
+		return resp, err
+	}
+}
+
+// startIndexSpan starts the span of an index load or save.
+func (t *telemetry) startIndexSpan(ctx context.Context, op string) (context.Context, trace.Span) {
+	if t == nil {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return t.tracer.Start(ctx, "keyring.index."+op, trace.WithAttributes(t.attrs...))
+}
+
+// recordIndex records the size of the index after a load or save, and ends
+// its span.
+func (t *telemetry) recordIndex(ctx context.Context, span trace.Span, size int, err error) {
+	if t == nil {
+		return
+	}
+	defer span.End()
+	if err != nil {
+		span.SetStatus(codes.Error, errorCategory(err))
+		return
+	}
+	span.SetAttributes(attrIndexSize.Int(size))
+	t.indexSize.Record(ctx, int64(size), metric.WithAttributes(t.attrs...))
+}
+
+// path returns the value recorded for path: a keyed hash when paths are
+// obfuscated, a plain hash if Config.HashTelemetryPaths is set, and the
+// path itself otherwise.
+func (t *telemetry) path(path string) string {
+	switch {
+	case t.p.paths != nil:
+		return t.p.paths.key(path)
+	case t.p.config.HashTelemetryPaths:
+		sum := sha256.Sum256([]byte(path))
+		return hex.EncodeToString(sum[:16])
+	default:
+		return path
+	}
+}
+
+// secretSize returns the number of bytes of secret material in secret.
+func secretSize(secret *vault.Secret) int {
+	n := len(secret.Value) + len(secret.ValueBytes)
+	for _, v := range secret.Fields {
+		n += len(v)
+	}
+	return n
+}
+
+// errorCategory returns a short, stable name for the kind of err, for use
+// in metrics and spans: "ok" for nil.
+func errorCategory(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, vault.ErrSecretNotFound):
+		return "not_found"
+	case errors.Is(err, ErrKeyringLocked):
+		return "locked"
+	case errors.Is(err, vault.ErrAccessDenied):
+		return "access_denied"
+	case errors.Is(err, ErrDaemonUnavailable):
+		return "unavailable"
+	case errors.Is(err, ErrTooLarge):
+		return "too_large"
+	case errors.Is(err, vault.ErrInvalidPath):
+		return "invalid_path"
+	case errors.Is(err, ErrPreconditionFailed), errors.Is(err, vault.ErrAlreadyExists):
+		return "conflict"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, vault.ErrClosed):
+		return "closed"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,195 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivault/vault"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newInstrumentedProvider returns a provider reporting to an in-memory span
+// recorder and metric reader.
+func newInstrumentedProvider(t *testing.T, b *memoryBackend, config Config) (*Provider, *tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	t.Helper()
+	useBackend(t, b)
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	config.ServiceName = "telemetry-test"
+	config.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	config.MeterProvider = sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	return New(config), spans, reader
+}
+
+// spanAttr returns the value of the attribute key of span.
+func spanAttr(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+// findSpan returns the first recorded span called name.
+func findSpan(t *testing.T, spans *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for _, span := range spans.Ended() {
+		if span.Name() == name {
+			return span
+		}
+	}
+	t.Fatalf("no %s span recorded", name)
+	return nil
+}
+
+// collect returns the metrics recorded by reader, by name.
+func collect(t *testing.T, reader *sdkmetric.ManualReader) map[string]metricdata.Metrics {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	metrics := make(map[string]metricdata.Metrics)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m
+		}
+	}
+	return metrics
+}
+
+func TestTelemetrySpans(t *testing.T) {
+	p, spans, _ := newInstrumentedProvider(t, newMemoryBackend(), Config{})
+	ctx := context.Background()
+
+	if err := p.Set(ctx, "db/password", &vault.Secret{Value: "hunter2"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if _, err := p.Get(ctx, "db/password"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	p.Get(ctx, "missing")
+
+	set := findSpan(t, spans, "keyring.Set")
+	if v, _ := spanAttr(set, attrPath); v.AsString() != "db/password" {
+		t.Errorf("Set span path = %q, want db/password", v.AsString())
+	}
+	if v, _ := spanAttr(set, attrPayloadSize); v.AsInt64() != 7 {
+		t.Errorf("Set span payload size = %d, want 7", v.AsInt64())
+	}
+	if v, _ := spanAttr(set, attrBackend); v.AsString() != p.Backend() {
+		t.Errorf("Set span backend = %q, want %q", v.AsString(), p.Backend())
+	}
+
+	// Index operations are children of the operation that made them.
+	save := findSpan(t, spans, "keyring.index.save")
+	if save.Parent().SpanID() != set.SpanContext().SpanID() {
+		t.Error("index save span is not a child of the Set span")
+	}
+	if v, _ := spanAttr(save, attrIndexSize); v.AsInt64() != 1 {
+		t.Errorf("index save size = %d, want 1", v.AsInt64())
+	}
+
+	for _, span := range spans.Ended() {
+		if span.Name() != "keyring.Get" {
+			continue
+		}
+		outcome, _ := spanAttr(span, attrOutcome)
+		path, _ := spanAttr(span, attrPath)
+		switch path.AsString() {
+		case "db/password":
+			if outcome.AsString() != "ok" || span.Status().Code == codes.Error {
+				t.Errorf("Get span outcome = %q, status %v; want ok", outcome.AsString(), span.Status())
+			}
+		case "missing":
+			if outcome.AsString() != "not_found" {
+				t.Errorf("Get span outcome = %q, want not_found", outcome.AsString())
+			}
+		}
+	}
+}
+
+func TestTelemetryHashedPaths(t *testing.T) {
+	p, spans, _ := newInstrumentedProvider(t, newMemoryBackend(), Config{HashTelemetryPaths: true})
+	p.Set(context.Background(), "db/password", &vault.Secret{Value: "v"})
+
+	v, _ := spanAttr(findSpan(t, spans, "keyring.Set"), attrPath)
+	if v.AsString() == "" || strings.Contains(v.AsString(), "password") {
+		t.Errorf("Set span path = %q, want a hash", v.AsString())
+	}
+}
+
+func TestTelemetryMetrics(t *testing.T) {
+	b := newMemoryBackend()
+	p, spans, reader := newInstrumentedProvider(t, b, Config{Cache: CacheConfig{TTL: time.Minute}})
+	ctx := context.Background()
+
+	p.Set(ctx, "a", &vault.Secret{Value: "1"})
+	p.Set(ctx, "b", &vault.Secret{Value: "2"})
+	p.Get(ctx, "a")
+	p.Get(ctx, "a")
+	b.failWith(func(op, service, user string) error {
+		if op == "Get" && user == "b" {
+			return errors.New("org.freedesktop.Secret.Error.IsLocked")
+		}
+		return nil
+	})
+	p.Get(ctx, "b")
+
+	metrics := collect(t, reader)
+
+	duration, ok := metrics["keyring.operation.duration"].Data.(metricdata.Histogram[float64])
+	if !ok {
+		t.Fatal("no operation duration histogram")
+	}
+	var count uint64
+	for _, dp := range duration.DataPoints {
+		count += dp.Count
+	}
+	if count != 5 {
+		t.Errorf("operation duration recorded %d operations, want 5", count)
+	}
+
+	errs, ok := metrics["keyring.operation.errors"].Data.(metricdata.Sum[int64])
+	if !ok || len(errs.DataPoints) != 1 {
+		t.Fatalf("operation errors = %+v, want one data point", metrics["keyring.operation.errors"].Data)
+	}
+	if op, _ := errs.DataPoints[0].Attributes.Value(attrOperation); op.AsString() != "Get" {
+		t.Errorf("error recorded for operation %q, want Get", op.AsString())
+	}
+	if span := findSpan(t, spans, "keyring.Get"); span.Status().Code == codes.Error &&
+		strings.Contains(span.Status().Description, "b") {
+		t.Errorf("span status %q names the path", span.Status().Description)
+	}
+
+	if size, ok := metrics["keyring.index.size"].Data.(metricdata.Gauge[int64]); !ok || size.DataPoints[0].Value != 2 {
+		t.Errorf("index size = %+v, want 2", metrics["keyring.index.size"].Data)
+	}
+
+	requests, ok := metrics["keyring.cache.requests"].Data.(metricdata.Sum[int64])
+	if !ok {
+		t.Fatal("no cache requests counter")
+	}
+	for _, dp := range requests.DataPoints {
+		result, _ := dp.Attributes.Value(attrCacheResult)
+		if result.AsString() == "hit" && dp.Value != 1 {
+			t.Errorf("cache hits = %d, want 1", dp.Value)
+		}
+	}
+}
+
+func TestTelemetryDisabled(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+	if p := New(Config{ServiceName: "telemetry-test"}); p.telemetry != nil {
+		t.Error("telemetry enabled without a tracer or meter provider")
+	}
+}
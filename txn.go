@@ -0,0 +1,426 @@
+package keyring
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/agentplexus/omnivault/vault"
+	zkeyring "github.com/zalando/go-keyring"
+)
+
+const (
+	// journalKey is the key used to store the journal of an in-flight transaction.
+	// It allows an interrupted transaction to be completed or undone by a later
+	// provider; see Recover.
+	journalKey = "__omnivault_journal__"
+
+	// Journal states.
+	journalPending   = "pending"
+	journalCommitted = "committed"
+)
+
+// ErrTxDone is returned when a Tx is used after its transaction has finished.
+var ErrTxDone = errors.New("keyring: transaction has already finished")
+
+// Tx stages writes for a transaction started with Provider.Txn.
+// Staged operations are only applied when the transaction function returns nil.
+type Tx struct {
+	p      *Provider
+	order  []string
+	staged map[string]*string // nil value stages a delete
+	done   bool
+}
+
+// Set stages storing secret at path.
+// The secret is encoded immediately, so later changes to it are not applied.
+func (tx *Tx) Set(path string, secret *vault.Secret) error {
+	if tx.done {
+		return vault.NewVaultError("Txn", path, tx.p.Name(), ErrTxDone)
+	}
+	path, err := tx.p.canonical("Txn", path)
+	if err != nil {
+		return err
+	}
+	value, err := tx.p.encode(secret)
+	if err != nil {
+		return vault.NewVaultError("Txn", path, tx.p.Name(), err)
+	}
+	tx.stage(path, &value)
+	return nil
+}
+
+// Delete stages removing the secret at path.
+// Deleting a secret that doesn't exist is not an error.
+func (tx *Tx) Delete(path string) error {
+	if tx.done {
+		return vault.NewVaultError("Txn", path, tx.p.Name(), ErrTxDone)
+	}
+	path, err := tx.p.canonical("Txn", path)
+	if err != nil {
+		return err
+	}
+	tx.stage(path, nil)
+	return nil
+}
+
+// Get retrieves a secret as seen by the transaction, including staged writes.
+func (tx *Tx) Get(ctx context.Context, path string) (*vault.Secret, error) {
+	if tx.done {
+		return nil, vault.NewVaultError("Txn", path, tx.p.Name(), ErrTxDone)
+	}
+	path, err := tx.p.canonical("Txn", path)
+	if err != nil {
+		return nil, err
+	}
+	if value, ok := tx.staged[path]; ok {
+		if value == nil {
+			return nil, vault.NewVaultError("Txn", path, tx.p.Name(), vault.ErrSecretNotFound)
+		}
+		return tx.p.decode(path, *value, tx.p.readETag(ctx, path, *value)), nil
+	}
+	return tx.p.get(ctx, "Txn", path)
+}
+
+// stage records the latest operation for path, keeping first-staged order.
+func (tx *Tx) stage(path string, value *string) {
+	if _, ok := tx.staged[path]; !ok {
+		tx.order = append(tx.order, path)
+	}
+	tx.staged[path] = value
+}
+
+// Txn runs fn and atomically applies the Set and Delete operations it stages.
+//
+// If fn returns an error nothing is written. Otherwise the previous value of
+// every affected path is snapshotted into a journal entry before any write,
+// and if a write fails the already-applied writes are rolled back. If the
+// process dies mid-commit, the next provider for the same service name to
+// write, or to call Recover, finds the journal and either completes or
+// undoes the transaction.
+//
+// The provider's write lock is held while fn runs, so fn must use tx rather
+// than calling other Provider methods.
+func (p *Provider) Txn(ctx context.Context, fn func(tx *Tx) error) error {
+	if err := p.ensureRecovered(ctx); err != nil {
+		return vault.NewVaultError("Txn", "", p.Name(), err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return vault.NewVaultError("Txn", "", p.Name(), vault.ErrClosed)
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	tx := &Tx{p: p, staged: make(map[string]*string)}
+	err := fn(tx)
+	tx.done = true
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return vault.NewVaultError("Txn", "", p.Name(), err)
+	}
+
+	_, err = p.handle(ctx, &Request{Op: "Txn", Paths: tx.order}, func(ctx context.Context, req *Request) (*Response, error) {
+		entries := make([]journalEntry, 0, len(tx.order))
+		for _, path := range tx.order {
+			entries = append(entries, journalEntry{Path: path, Next: tx.staged[path]})
+		}
+		return nil, p.commit(ctx, "Txn", entries)
+	})
+	return err
+}
+
+// journal is the crash-recovery record of an in-flight transaction.
+type journal struct {
+	ID      string         `json:"id"`
+	State   string         `json:"state"`
+	Entries []journalEntry `json:"entries"`
+}
+
+// journalEntry records a single path's value before and after a transaction.
+// A nil Prev or Next means the secret is absent.
+type journalEntry struct {
+	Path string  `json:"path"`
+	Prev *string `json:"prev,omitempty"`
+	Next *string `json:"next,omitempty"`
+}
+
+// commit applies entries atomically, journaling them first so an interrupted
+// commit can be recovered. Each entry's Next is applied in order; Prev is
+// filled in from the backend. The caller must hold p.mu for writing.
+//
+// Once the journal is written, the writes, and rolling them back or
+// finishing the commit, are not interrupted by ctx, so a cancelled commit
+// doesn't leave a half-applied transaction behind. They are still bounded
+// by Config.DefaultTimeout; a write abandoned that way may take effect
+// later, so the journal is kept for recovery to undo it again. Whenever the
+// journal is kept, the provider recovers it before its next write, so that
+// recovery never undoes a write made after the commit.
+func (p *Provider) commit(ctx context.Context, op string, entries []journalEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	p.journalMu.Lock()
+	defer p.journalMu.Unlock()
+
+	// Snapshot previous values
+	for i := range entries {
+		value, err := p.read(ctx, entries[i].Path)
+		switch {
+		case err == nil:
+			entries[i].Prev = &value
+		case !errors.Is(err, zkeyring.ErrNotFound):
+			return vault.NewVaultError(op, entries[i].Path, p.Name(), fmt.Errorf("snapshot: %w", err))
+		}
+	}
+
+	j := &journal{ID: newTxnID(), State: journalPending, Entries: entries}
+	if err := p.saveJournal(ctx, j); err != nil {
+		return vault.NewVaultError(op, "", p.Name(), fmt.Errorf("write journal: %w", err))
+	}
+
+	apply, cancel := p.detach(ctx)
+	defer cancel()
+	var failed int
+	var applyErr error
+	for i, e := range entries {
+		if err := p.applyValue(apply, e.Path, e.Next); err != nil {
+			failed, applyErr = i, err
+			break
+		}
+	}
+
+	// Rolling back or finishing gets its own time budget, whatever the
+	// writes used up.
+	settle, cancel := p.detach(ctx)
+	defer cancel()
+
+	if applyErr != nil {
+		err := vault.NewVaultError(op, entries[failed].Path, p.Name(), applyErr)
+		// The failed write may have taken effect anyway, so it is undone
+		// along with the ones before it.
+		return p.abort(settle, op, entries[:failed+1], err, applyErr)
+	}
+
+	// From here on, recovery completes the transaction rather than undoing it.
+	j.State = journalCommitted
+	if saveErr := p.saveJournal(settle, j); saveErr != nil {
+		// The journal still says pending, so recovery would undo the
+		// transaction; undo it now and report the commit as failed.
+		err := vault.NewVaultError(op, "", p.Name(), fmt.Errorf("write journal: %w", saveErr))
+		return p.abort(settle, op, entries, err, saveErr)
+	}
+
+	added, removed := indexChanges(entries, false)
+	p.updateIndex(settle, added, removed)
+	p.deleteJournal(settle)
+	return nil
+}
+
+// abort rolls back entries after the commit failed with err, because of
+// cause, and returns the error to report. The journal is kept if the undo
+// fails, or if cause was a timeout, since the call that timed out may still
+// take effect and need undoing again.
+func (p *Provider) abort(ctx context.Context, op string, entries []journalEntry, err, cause error) error {
+	if rbErr := p.rollback(ctx, entries); rbErr != nil {
+		// Leave the journal in place so recovery can finish the undo.
+		p.recovered.Store(false)
+		return errors.Join(err, vault.NewVaultError(op, "", p.Name(), fmt.Errorf("rollback: %w", rbErr)))
+	}
+	if isContextError(cause) {
+		p.recovered.Store(false)
+		return err
+	}
+	p.deleteJournal(ctx)
+	return err
+}
+
+// rollback restores the previous values of entries in reverse order.
+func (p *Provider) rollback(ctx context.Context, entries []journalEntry) error {
+	var errs []error
+	for i := len(entries) - 1; i >= 0; i-- {
+		if err := p.applyValue(ctx, entries[i].Path, entries[i].Prev); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", entries[i].Path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// rollforward applies the new values of entries in order.
+func (p *Provider) rollforward(ctx context.Context, entries []journalEntry) error {
+	var errs []error
+	for _, e := range entries {
+		if err := p.applyValue(ctx, e.Path, e.Next); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e.Path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// applyValue stores value at path, or deletes path if value is nil.
+func (p *Provider) applyValue(ctx context.Context, path string, value *string) error {
+	if value == nil {
+		err := p.remove(ctx, path)
+		if errors.Is(err, zkeyring.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	return p.write(ctx, path, *value)
+}
+
+// Recover completes or undoes a transaction that a crashed process left
+// half-applied, in this profile and the profiles it falls back to.
+//
+// A provider does this by itself before its first write, so Recover is
+// only needed for reads not to see a half-applied transaction. New never
+// touches the keyring, since it may block on an unlock prompt or a stalled
+// daemon; call Recover with a deadline at startup instead.
+func (p *Provider) Recover(ctx context.Context) error {
+	var errs []error
+	for profile := p; profile != nil; profile = profile.fallback {
+		if err := profile.ensureRecovered(ctx); err != nil {
+			errs = append(errs, vault.NewVaultError("Recover", "", profile.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ensureRecovered recovers the journal unless that has already been done.
+// A failed recovery, other than of a journal that can't be read, is retried
+// by the next call; until then writes must fail, since recovery would undo
+// or overwrite them. A journal that can't be decoded is reported once and
+// then left alone. It takes p.mu for writing, so it must be called before
+// the caller takes p.mu.
+func (p *Provider) ensureRecovered(ctx context.Context) error {
+	if p.recovered.Load() {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return vault.ErrClosed
+	}
+	if p.recovered.Load() {
+		return nil
+	}
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	err := p.recoverJournal(ctx)
+	if err == nil || errors.Is(err, errBadJournal) {
+		p.recovered.Store(true)
+	}
+	if err != nil {
+		p.reportIndexError("recover", err)
+		return fmt.Errorf("recover: %w", err)
+	}
+	return nil
+}
+
+// errBadJournal marks a journal that can't be decoded, which no retry can
+// recover.
+var errBadJournal = errors.New("unreadable transaction journal")
+
+// recoverJournal completes or undoes a transaction interrupted by a crash.
+// Pending transactions are undone; committed ones are completed. The
+// caller must hold p.mu for writing.
+func (p *Provider) recoverJournal(ctx context.Context) error {
+	value, err := p.read(ctx, journalKey)
+	if err != nil {
+		if errors.Is(err, zkeyring.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	data, err := p.openRecord(journalKey, value)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errBadJournal, err)
+	}
+	var j journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return fmt.Errorf("%w: %w", errBadJournal, err)
+	}
+
+	undo := j.State != journalCommitted
+	p.log.DebugContext(ctx, "keyring: recovering interrupted transaction",
+		slog.String("id", j.ID), slog.Bool("undo", undo), slog.Int("entries", len(j.Entries)))
+	if undo {
+		err = p.rollback(ctx, j.Entries)
+	} else {
+		err = p.rollforward(ctx, j.Entries)
+	}
+	if err != nil {
+		// Keep the journal so recovery is retried.
+		return fmt.Errorf("transaction %s: %w", j.ID, err)
+	}
+
+	added, removed := indexChanges(j.Entries, undo)
+	p.updateIndex(ctx, added, removed)
+	return p.deleteJournal(ctx)
+}
+
+// indexChanges returns the index additions and removals implied by entries,
+// using their previous values if undo is set and their new values otherwise.
+func indexChanges(entries []journalEntry, undo bool) (added, removed []string) {
+	for _, e := range entries {
+		target := e.Next
+		if undo {
+			target = e.Prev
+		}
+		if target == nil {
+			removed = append(removed, e.Path)
+		} else {
+			added = append(added, e.Path)
+		}
+	}
+	return added, removed
+}
+
+// saveJournal writes the transaction journal to the keyring.
+func (p *Provider) saveJournal(ctx context.Context, j *journal) error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	return p.write(ctx, journalKey, p.sealRecord(journalKey, data))
+}
+
+// deleteJournal removes the transaction journal from the keyring. If that
+// fails, the journal is recovered again before the next write, which would
+// otherwise be undone or overwritten by a later recovery.
+func (p *Provider) deleteJournal(ctx context.Context) error {
+	err := p.remove(ctx, journalKey)
+	if err != nil && !errors.Is(err, zkeyring.ErrNotFound) {
+		p.recovered.Store(false)
+		p.reportIndexError("journal", err)
+		return fmt.Errorf("delete journal: %w", err)
+	}
+	return nil
+}
+
+// newTxnID returns a random transaction identifier.
+func newTxnID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// isReservedKey reports whether key is used internally by the provider.
+func isReservedKey(key string) bool {
+	return key == indexKey || key == journalKey || key == healthKey || key == etagKeyName ||
+		strings.HasPrefix(key, auditHeadPrefix)
+}
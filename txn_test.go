@@ -0,0 +1,511 @@
+package keyring
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivault/vault"
+	zkeyring "github.com/zalando/go-keyring"
+)
+
+func TestProvider_Txn(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBackend()
+	useBackend(t, b)
+
+	p := New(Config{ServiceName: "test-txn"})
+	defer p.Close()
+
+	if err := p.Set(ctx, "old", &vault.Secret{Value: "gone-soon"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	err := p.Txn(ctx, func(tx *Tx) error {
+		if err := tx.Set("db/user", &vault.Secret{Value: "admin"}); err != nil {
+			return err
+		}
+		if err := tx.Set("db/pass", &vault.Secret{Value: "hunter2"}); err != nil {
+			return err
+		}
+		if err := tx.Delete("old"); err != nil {
+			return err
+		}
+
+		// Reads see staged writes
+		secret, err := tx.Get(ctx, "db/pass")
+		if err != nil || secret.Value != "hunter2" {
+			t.Errorf("expected staged value, got %v, %v", secret, err)
+		}
+		if _, err := tx.Get(ctx, "old"); !errors.Is(err, vault.ErrSecretNotFound) {
+			t.Errorf("expected staged delete to hide old, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Txn failed: %v", err)
+	}
+
+	for path, want := range map[string]string{"db/user": "admin", "db/pass": "hunter2"} {
+		secret, err := p.Get(ctx, path)
+		if err != nil || secret.Value != want {
+			t.Errorf("expected %s=%q, got %v, %v", path, want, secret, err)
+		}
+	}
+	if exists, _ := p.Exists(ctx, "old"); exists {
+		t.Error("expected old to be deleted")
+	}
+
+	list, _ := p.List(ctx, "")
+	if len(list) != 2 {
+		t.Errorf("expected 2 indexed paths, got %v", list)
+	}
+	if _, err := b.Get("test-txn", journalKey); !errors.Is(err, zkeyring.ErrNotFound) {
+		t.Errorf("expected journal to be removed after commit, got %v", err)
+	}
+}
+
+func TestProvider_Txn_FunctionError(t *testing.T) {
+	ctx := context.Background()
+	useBackend(t, newMemoryBackend())
+
+	p := New(Config{ServiceName: "test-txn-fn-error"})
+	defer p.Close()
+
+	errAbort := errors.New("abort")
+	var leaked *Tx
+	err := p.Txn(ctx, func(tx *Tx) error {
+		leaked = tx
+		_ = tx.Set("a", &vault.Secret{Value: "1"})
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("expected errAbort, got %v", err)
+	}
+	if exists, _ := p.Exists(ctx, "a"); exists {
+		t.Error("expected nothing to be written")
+	}
+	if err := leaked.Set("b", &vault.Secret{}); !errors.Is(err, ErrTxDone) {
+		t.Errorf("expected ErrTxDone, got %v", err)
+	}
+}
+
+func TestProvider_Txn_Rollback(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBackend()
+	useBackend(t, b)
+
+	p := New(Config{ServiceName: "test-txn-rollback"})
+	defer p.Close()
+
+	if err := p.Set(ctx, "user", &vault.Secret{Value: "old-user"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := p.Set(ctx, "pass", &vault.Secret{Value: "old-pass"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	errBoom := errors.New("boom")
+	failed := false
+	b.failWith(func(op, _, user string) error {
+		if op == "Set" && user == "pass" && !failed {
+			failed = true
+			return errBoom
+		}
+		return nil
+	})
+
+	err := p.Txn(ctx, func(tx *Tx) error {
+		_ = tx.Set("user", &vault.Secret{Value: "new-user"})
+		_ = tx.Set("extra", &vault.Secret{Value: "new"})
+		_ = tx.Set("pass", &vault.Secret{Value: "new-pass"})
+		return nil
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected errBoom, got %v", err)
+	}
+	b.failWith(nil)
+
+	secret, err := p.Get(ctx, "user")
+	if err != nil || secret.Value != "old-user" {
+		t.Errorf("expected user to be rolled back, got %v, %v", secret, err)
+	}
+	if exists, _ := p.Exists(ctx, "extra"); exists {
+		t.Error("expected extra to be rolled back")
+	}
+	if secret, err := p.Get(ctx, "pass"); err != nil || secret.Value != "old-pass" {
+		t.Errorf("expected pass to be restored, got %v, %v", secret, err)
+	}
+	if _, err := b.Get("test-txn-rollback", journalKey); !errors.Is(err, zkeyring.ErrNotFound) {
+		t.Errorf("expected journal to be removed after rollback, got %v", err)
+	}
+}
+
+func TestProvider_Txn_CallerDeadline(t *testing.T) {
+	useBackend(t, gatedBackend{newMemoryBackend(), func(op, user string) {
+		if op == "Set" && user == "a" {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}})
+	p := New(Config{ServiceName: "test-txn-deadline"})
+	defer p.Close()
+	bg := context.Background()
+	_ = p.Set(bg, "a", &vault.Secret{Value: "a0"})
+	_ = p.Set(bg, "b", &vault.Secret{Value: "b0"})
+
+	// The deadline passes while "a" is being written; the commit carries on
+	// rather than stopping between "a" and "b".
+	ctx, cancel := context.WithTimeout(bg, 50*time.Millisecond)
+	defer cancel()
+	err := p.Txn(ctx, func(tx *Tx) error {
+		_ = tx.Set("a", &vault.Secret{Value: "a1"})
+		return tx.Set("b", &vault.Secret{Value: "b1"})
+	})
+	if err != nil {
+		t.Fatalf("Txn() error = %v", err)
+	}
+	for path, want := range map[string]string{"a": "a1", "b": "b1"} {
+		if secret, err := p.Get(bg, path); err != nil || secret.Value != want {
+			t.Errorf("Get(%q) = %v, %v; want %q", path, secret, err, want)
+		}
+	}
+}
+
+func TestProvider_Txn_AbandonedWrite(t *testing.T) {
+	mem := newMemoryBackend()
+	var stalled atomic.Bool
+	useBackend(t, gatedBackend{mem, func(op, user string) {
+		// The first write of "b" in the transaction outlives the timeout,
+		// then takes effect.
+		if op == "Set" && user == "b" && mem.count("Set", "b") == 1 && stalled.CompareAndSwap(false, true) {
+			time.Sleep(150 * time.Millisecond)
+		}
+	}})
+	ctx := context.Background()
+	config := Config{ServiceName: "test-txn-abandoned", DefaultTimeout: 50 * time.Millisecond}
+	p := New(config)
+	defer p.Close()
+	_ = p.Set(ctx, "a", &vault.Secret{Value: "a0"})
+	_ = p.Set(ctx, "b", &vault.Secret{Value: "b0"})
+
+	err := p.Txn(ctx, func(tx *Tx) error {
+		_ = tx.Set("a", &vault.Secret{Value: "a1"})
+		return tx.Set("b", &vault.Secret{Value: "b1"})
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Txn() error = %v, want context.DeadlineExceeded", err)
+	}
+	if secret, err := p.Get(ctx, "a"); err != nil || secret.Value != "a0" {
+		t.Errorf("Get(a) = %v, %v; want a0", secret, err)
+	}
+	if _, err := mem.Get("test-txn-abandoned", journalKey); err != nil {
+		t.Fatalf("journal removed while a write may still land: %v", err)
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if value, _ := mem.Get("test-txn-abandoned", "b"); value == "b1" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("abandoned write didn't land")
+		}
+	}
+
+	// Recovery undoes the late write.
+	p2 := New(config)
+	defer p2.Close()
+	if err := p2.Recover(ctx); err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+	for path, want := range map[string]string{"a": "a0", "b": "b0"} {
+		if secret, err := p2.Get(ctx, path); err != nil || secret.Value != want {
+			t.Errorf("after recovery Get(%q) = %v, %v; want %q", path, secret, err, want)
+		}
+	}
+}
+
+func TestProvider_Txn_KeptJournal(t *testing.T) {
+	errBoom := errors.New("boom")
+	tests := []struct {
+		name    string
+		wantErr bool
+		// fail makes the Txn leave its journal behind, and returns once
+		// the backend is back to normal.
+		fail func(mem *memoryBackend, stall *atomic.Bool) func()
+	}{
+		{"write timed out", true, func(mem *memoryBackend, stall *atomic.Bool) func() {
+			stall.Store(true)
+			return func() {
+				for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+					if value, _ := mem.Get("test-txn-kept", "b"); value == "b1" {
+						return
+					}
+				}
+				t.Fatal("abandoned write didn't land")
+			}
+		}},
+		{"rollback failed", true, func(mem *memoryBackend, _ *atomic.Bool) func() {
+			mem.failWith(func(op, _, user string) error {
+				if op == "Set" && user == "b" {
+					return errBoom
+				}
+				return nil
+			})
+			return func() { mem.failWith(nil) }
+		}},
+		{"journal delete failed", false, func(mem *memoryBackend, _ *atomic.Bool) func() {
+			mem.failWith(func(op, _, user string) error {
+				if op == "Delete" && user == journalKey {
+					return errBoom
+				}
+				return nil
+			})
+			return func() { mem.failWith(nil) }
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mem := newMemoryBackend()
+			var stall atomic.Bool
+			useBackend(t, gatedBackend{mem, func(op, user string) {
+				if op == "Set" && user == "b" && stall.CompareAndSwap(true, false) {
+					time.Sleep(150 * time.Millisecond)
+				}
+			}})
+			ctx := context.Background()
+			config := Config{ServiceName: "test-txn-kept", DefaultTimeout: 50 * time.Millisecond}
+			p := New(config)
+			defer p.Close()
+			_ = p.Set(ctx, "a", &vault.Secret{Value: "a0"})
+			_ = p.Set(ctx, "b", &vault.Secret{Value: "b0"})
+
+			restore := tt.fail(mem, &stall)
+			err := p.Txn(ctx, func(tx *Tx) error {
+				_ = tx.Set("a", &vault.Secret{Value: "a1"})
+				return tx.Set("b", &vault.Secret{Value: "b1"})
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Txn() error = %v, want error %v", err, tt.wantErr)
+			}
+			if _, err := mem.Get("test-txn-kept", journalKey); err != nil {
+				t.Fatalf("journal = %v, want it kept", err)
+			}
+			restore()
+
+			// The next write recovers the journal first, so no later
+			// recovery can undo it.
+			if err := p.Set(ctx, "a", &vault.Secret{Value: "a-later"}); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			p2 := New(config)
+			defer p2.Close()
+			if err := p2.Set(ctx, "other", &vault.Secret{Value: "v"}); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if secret, err := p2.Get(ctx, "a"); err != nil || secret.Value != "a-later" {
+				t.Errorf("Get(a) = %v, %v; want a-later", secret, err)
+			}
+		})
+	}
+}
+
+func TestProvider_Txn_CommitStateFailure(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBackend()
+	useBackend(t, b)
+	p := New(Config{ServiceName: "test-txn-state"})
+	defer p.Close()
+	_ = p.Set(ctx, "a", &vault.Secret{Value: "a0"})
+
+	errBoom := errors.New("boom")
+	saves := 0
+	b.failWith(func(op, _, user string) error {
+		if op == "Set" && user == journalKey {
+			if saves++; saves == 2 {
+				return errBoom
+			}
+		}
+		return nil
+	})
+	err := p.Txn(ctx, func(tx *Tx) error {
+		_ = tx.Set("a", &vault.Secret{Value: "a1"})
+		return tx.Set("b", &vault.Secret{Value: "b1"})
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("Txn() error = %v, want %v", err, errBoom)
+	}
+	b.failWith(nil)
+
+	if secret, err := p.Get(ctx, "a"); err != nil || secret.Value != "a0" {
+		t.Errorf("Get(a) = %v, %v; want a0", secret, err)
+	}
+	if exists, _ := p.Exists(ctx, "b"); exists {
+		t.Error("expected b to be rolled back")
+	}
+	if _, err := b.Get("test-txn-state", journalKey); !errors.Is(err, zkeyring.ErrNotFound) {
+		t.Errorf("expected journal to be removed after rollback, got %v", err)
+	}
+}
+
+func TestProvider_Txn_Recovery(t *testing.T) {
+	prev := "old"
+	next := "new"
+
+	tests := []struct {
+		name  string
+		state string
+		want  string
+	}{
+		{"pending transaction is undone", journalPending, prev},
+		{"committed transaction is completed", journalCommitted, next},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			b := newMemoryBackend()
+			useBackend(t, b)
+
+			// Simulate a crash after "a" was written but before "b" was created
+			_ = b.Set("test-txn-recover", "a", next)
+			data, _ := json.Marshal(journal{
+				ID:    "abc",
+				State: tt.state,
+				Entries: []journalEntry{
+					{Path: "a", Prev: &prev, Next: &next},
+					{Path: "b", Next: &next},
+				},
+			})
+			_ = b.Set("test-txn-recover", journalKey, string(data))
+
+			p := New(Config{ServiceName: "test-txn-recover"})
+			defer p.Close()
+			if err := p.Recover(ctx); err != nil {
+				t.Fatalf("Recover() error = %v", err)
+			}
+
+			secret, err := p.Get(ctx, "a")
+			if err != nil || secret.Value != tt.want {
+				t.Errorf("expected a=%q, got %v, %v", tt.want, secret, err)
+			}
+			exists, _ := p.Exists(ctx, "b")
+			if exists != (tt.state == journalCommitted) {
+				t.Errorf("unexpected existence of b: %v", exists)
+			}
+			if _, err := b.Get("test-txn-recover", journalKey); !errors.Is(err, zkeyring.ErrNotFound) {
+				t.Errorf("expected journal to be removed after recovery, got %v", err)
+			}
+		})
+	}
+}
+
+func TestProvider_Txn_RecoveryIsLazy(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBackend()
+	useBackend(t, b)
+
+	prev, next := "old", "new"
+	_ = b.Set("test-txn-lazy", "a", next)
+	data, _ := json.Marshal(journal{ID: "abc", State: journalPending, Entries: []journalEntry{{Path: "a", Prev: &prev, Next: &next}}})
+	_ = b.Set("test-txn-lazy", journalKey, string(data))
+
+	p := New(Config{ServiceName: "test-txn-lazy"})
+	defer p.Close()
+	if n := b.count("Get", journalKey); n != 0 {
+		t.Fatalf("New read the journal %d times, want 0", n)
+	}
+
+	// The first write recovers the journal before writing.
+	if err := p.Set(ctx, "other", &vault.Secret{Value: "v"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if secret, err := p.Get(ctx, "a"); err != nil || secret.Value != prev {
+		t.Errorf("expected a=%q after the first write, got %v, %v", prev, secret, err)
+	}
+	_ = p.Set(ctx, "other", &vault.Secret{Value: "v2"})
+	if n := b.count("Get", journalKey); n != 1 {
+		t.Errorf("journal read %d times, want once", n)
+	}
+}
+
+func TestProvider_Txn_RecoveryFailureBlocksWrites(t *testing.T) {
+	ctx := context.Background()
+	b := newMemoryBackend()
+	useBackend(t, b)
+
+	prev, next := "old", "new"
+	_ = b.Set("test-txn-blocked", "a", next)
+	data, _ := json.Marshal(journal{ID: "abc", State: journalPending, Entries: []journalEntry{{Path: "a", Prev: &prev, Next: &next}}})
+	_ = b.Set("test-txn-blocked", journalKey, string(data))
+
+	p := New(Config{ServiceName: "test-txn-blocked"})
+	defer p.Close()
+
+	locked := errors.New("journal locked")
+	b.failWith(func(op, _, user string) error {
+		if user == journalKey {
+			return locked
+		}
+		return nil
+	})
+	if err := p.Set(ctx, "a", &vault.Secret{Value: "user-new"}); !errors.Is(err, locked) {
+		t.Errorf("Set() with a failing recovery error = %v, want %v", err, locked)
+	}
+	err := p.Txn(ctx, func(tx *Tx) error {
+		return tx.Set("a", &vault.Secret{Value: "txn-new"})
+	})
+	if !errors.Is(err, locked) {
+		t.Errorf("Txn() with a failing recovery error = %v, want %v", err, locked)
+	}
+	if value, _ := b.Get("test-txn-blocked", "a"); value != next {
+		t.Fatalf("a = %q while recovery fails, want it untouched", value)
+	}
+
+	// Once recovery succeeds, writes go ahead and aren't undone later.
+	b.failWith(nil)
+	if err := p.Set(ctx, "a", &vault.Secret{Value: "user-new"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := New(Config{ServiceName: "test-txn-blocked"}).Recover(ctx); err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+	if secret, err := p.Get(ctx, "a"); err != nil || secret.Value != "user-new" {
+		t.Errorf("expected a=%q, got %v, %v", "user-new", secret, err)
+	}
+}
+
+func TestProvider_Recover_Deadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	useBackend(t, gatedBackend{newMemoryBackend(), func(op, user string) {
+		if user == journalKey {
+			<-release
+		}
+	}})
+
+	// New returns even though the keyring is stuck.
+	p := New(Config{ServiceName: "test-txn-stuck"})
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.Recover(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Recover() error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestProvider_Txn_ReservedPath(t *testing.T) {
+	ctx := context.Background()
+	p := New(Config{ServiceName: "test-txn-reserved"})
+	defer p.Close()
+
+	err := p.Txn(ctx, func(tx *Tx) error {
+		return tx.Set(journalKey, &vault.Secret{Value: "x"})
+	})
+	if !errors.Is(err, vault.ErrInvalidPath) {
+		t.Errorf("expected ErrInvalidPath, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package keyring
+
+import (
+	"context"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+// UnlockOptions controls how Provider.Unlock unlocks the keyring.
+type UnlockOptions struct {
+	// OnPrompt is called when unlocking requires the Secret Service to show
+	// an interactive prompt. Returning nil shows the prompt and waits for the
+	// user; returning an error dismisses it, and Unlock fails with that error.
+	// If nil, the prompt is shown. Headless services should either set
+	// Password or return an error here rather than wait for a user who will
+	// never answer.
+	OnPrompt func(ctx context.Context) error
+
+	// WindowID is the platform window identifier the prompt is shown for,
+	// e.g. an X11 window ID. It may be empty.
+	WindowID string
+
+	// Password, if set, supplies the collection password so the collection
+	// is unlocked without a prompt. The returned slice is zeroed after use.
+	// It requires gnome-keyring, and the password is sent to the daemon over
+	// the session bus.
+	Password func(ctx context.Context) ([]byte, error)
+}
+
+// locker is implemented by backends whose secrets live in a lockable
+// collection, such as the Secret Service on Linux.
+type locker interface {
+	// unlock unlocks the collection holding the provider's secrets.
+	unlock(ctx context.Context, opts UnlockOptions) error
+
+	// lock locks the collection holding the provider's secrets.
+	lock(ctx context.Context) error
+}
+
+// Unlock unlocks the Secret Service collection holding the provider's
+// secrets, using the Secret Service Unlock method or, if opts.Password is
+// set, gnome-keyring's password unlock.
+//
+// Unlocking an already unlocked collection is a no-op. A dismissed prompt
+// returns an error matching ErrKeyringLocked. On platforms without lockable
+// collections Unlock returns vault.ErrNotSupported.
+func (p *Provider) Unlock(ctx context.Context, opts UnlockOptions) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return vault.NewVaultError("Unlock", "", p.Name(), vault.ErrClosed)
+	}
+	l, ok := p.backend.(locker)
+	if !ok {
+		return vault.NewVaultError("Unlock", "", p.Name(), vault.ErrNotSupported)
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	if err := l.unlock(ctx, opts); err != nil {
+		return vault.NewVaultError("Unlock", "", p.Name(), classify(err))
+	}
+	return nil
+}
+
+// Lock locks the Secret Service collection holding the provider's secrets.
+// Subsequent operations fail with ErrKeyringLocked or show an unlock prompt,
+// depending on the Secret Service provider. On platforms without lockable
+// collections Lock returns vault.ErrNotSupported.
+func (p *Provider) Lock(ctx context.Context) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return vault.NewVaultError("Lock", "", p.Name(), vault.ErrClosed)
+	}
+	l, ok := p.backend.(locker)
+	if !ok {
+		return vault.NewVaultError("Lock", "", p.Name(), vault.ErrNotSupported)
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+	if err := l.lock(ctx); err != nil {
+		return vault.NewVaultError("Lock", "", p.Name(), classify(err))
+	}
+	return nil
+}
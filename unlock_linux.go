@@ -0,0 +1,211 @@
+//go:build linux
+
+package keyring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/agentplexus/omnivault/vault"
+	dbus "github.com/godbus/dbus/v5"
+)
+
+const (
+	secretPromptIface      = "org.freedesktop.Secret.Prompt"
+	secretSessionIface     = "org.freedesktop.Secret.Session"
+	secretCollectionPrefix = "/org/freedesktop/secrets/collection/"
+	gnomeKeyringIface      = "org.gnome.keyring.InternalUnsupportedGuiltRiddenInterface"
+)
+
+// secretServiceSecret is the Secret Service secret struct, (oayays).
+type secretServiceSecret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// unlock unlocks the login collection used by go-keyring.
+func (osBackend) unlock(ctx context.Context, opts UnlockOptions) error {
+	return unlockCollection(ctx, loginCollection, opts)
+}
+
+// lock locks the login collection used by go-keyring.
+func (osBackend) lock(ctx context.Context) error {
+	return lockCollection(ctx, loginCollection)
+}
+
+// unlockCollection unlocks the collection returned by resolve.
+func unlockCollection(ctx context.Context, resolve collectionResolver, opts UnlockOptions) error {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	collection, err := resolve(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if opts.Password != nil {
+		return unlockWithPassword(ctx, conn, collection, opts.Password)
+	}
+
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	err = conn.Object(secretServiceName, secretServicePath).CallWithContext(ctx,
+		secretServiceIface+".Unlock", 0, []dbus.ObjectPath{collection}).
+		Store(&unlocked, &prompt)
+	if err != nil {
+		return err
+	}
+	if prompt == "/" {
+		return nil // Already unlocked
+	}
+
+	if opts.OnPrompt != nil {
+		if err := opts.OnPrompt(ctx); err != nil {
+			_ = conn.Object(secretServiceName, prompt).CallWithContext(ctx, secretPromptIface+".Dismiss", 0).Err
+			return err
+		}
+	}
+	_, err = runPrompt(ctx, conn, prompt, opts.WindowID)
+	return err
+}
+
+// lockCollection locks the collection returned by resolve.
+func lockCollection(ctx context.Context, resolve collectionResolver) error {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	collection, err := resolve(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	var locked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	err = conn.Object(secretServiceName, secretServicePath).CallWithContext(ctx,
+		secretServiceIface+".Lock", 0, []dbus.ObjectPath{collection}).
+		Store(&locked, &prompt)
+	if err != nil {
+		return err
+	}
+	if prompt == "/" {
+		return nil
+	}
+	_, err = runPrompt(ctx, conn, prompt, "")
+	return err
+}
+
+// collectionResolver returns the path of the collection to operate on.
+type collectionResolver func(ctx context.Context, conn *dbus.Conn) (dbus.ObjectPath, error)
+
+// loginCollection returns the path of the collection go-keyring stores
+// secrets in: the "login" collection if it exists, else the default alias.
+func loginCollection(ctx context.Context, conn *dbus.Conn) (dbus.ObjectPath, error) {
+	service := conn.Object(secretServiceName, secretServicePath)
+
+	login := dbus.ObjectPath(secretCollectionPrefix + "login")
+	if v, err := service.GetProperty(secretServiceIface + ".Collections"); err == nil {
+		var paths []dbus.ObjectPath
+		if v.Store(&paths) == nil {
+			for _, path := range paths {
+				if path == login {
+					return login, nil
+				}
+			}
+		}
+	}
+
+	var path dbus.ObjectPath
+	if err := service.CallWithContext(ctx, secretServiceIface+".ReadAlias", 0, "default").Store(&path); err != nil {
+		return "", err
+	}
+	if path == "/" {
+		return "", fmt.Errorf("%w: no default collection", ErrDaemonUnavailable)
+	}
+	return path, nil
+}
+
+// runPrompt shows a Secret Service prompt, waits for it to complete and
+// returns its result. The prompt is dismissed if ctx is done first.
+func runPrompt(ctx context.Context, conn *dbus.Conn, prompt dbus.ObjectPath, windowID string) (dbus.Variant, error) {
+	match := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(prompt),
+		dbus.WithMatchInterface(secretPromptIface),
+		dbus.WithMatchMember("Completed"),
+	}
+	if err := conn.AddMatchSignalContext(ctx, match...); err != nil {
+		return dbus.Variant{}, err
+	}
+	defer conn.RemoveMatchSignal(match...)
+
+	signals := make(chan *dbus.Signal, 1)
+	conn.Signal(signals)
+	defer conn.RemoveSignal(signals)
+
+	obj := conn.Object(secretServiceName, prompt)
+	if err := obj.CallWithContext(ctx, secretPromptIface+".Prompt", 0, windowID).Err; err != nil {
+		return dbus.Variant{}, err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			_ = obj.Call(secretPromptIface+".Dismiss", 0).Err
+			return dbus.Variant{}, ctx.Err()
+		case sig, ok := <-signals:
+			if !ok {
+				return dbus.Variant{}, dbus.ErrClosed
+			}
+			if sig.Path != prompt || sig.Name != secretPromptIface+".Completed" || len(sig.Body) == 0 {
+				continue
+			}
+			if dismissed, _ := sig.Body[0].(bool); dismissed {
+				return dbus.Variant{}, &BackendError{
+					Kind: ErrKeyringLocked,
+					Hint: errorHint(ErrKeyringLocked),
+					Err:  errors.New("Secret Service prompt was dismissed"),
+				}
+			}
+			var result dbus.Variant
+			if len(sig.Body) > 1 {
+				result, _ = sig.Body[1].(dbus.Variant)
+			}
+			return result, nil
+		}
+	}
+}
+
+// unlockWithPassword unlocks collection with its password through
+// gnome-keyring's private interface, which the Secret Service spec lacks.
+func unlockWithPassword(ctx context.Context, conn *dbus.Conn, collection dbus.ObjectPath, source func(context.Context) ([]byte, error)) error {
+	password, err := source(ctx)
+	if err != nil {
+		return err
+	}
+	defer clear(password)
+
+	session, err := openSession(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer closeSession(conn, session)
+
+	secret := secretServiceSecret{
+		Session:     session,
+		Parameters:  []byte{},
+		Value:       password,
+		ContentType: "text/plain; charset=utf8",
+	}
+	err = conn.Object(secretServiceName, secretServicePath).CallWithContext(ctx, gnomeKeyringIface+".UnlockWithMasterPassword", 0, collection, secret).Err
+	if name, ok := dbusErrorName(err); ok && name == "org.freedesktop.DBus.Error.UnknownMethod" {
+		return fmt.Errorf("%w: password unlock requires gnome-keyring", vault.ErrNotSupported)
+	}
+	return err
+}
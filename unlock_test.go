@@ -0,0 +1,124 @@
+package keyring
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/agentplexus/omnivault/vault"
+	dbus "github.com/godbus/dbus/v5"
+)
+
+// lockableBackend is a memory backend with a lockable collection.
+type lockableBackend struct {
+	*memoryBackend
+	locked   bool
+	password string
+}
+
+func newLockableBackend(password string) *lockableBackend {
+	b := &lockableBackend{memoryBackend: newMemoryBackend(), locked: true, password: password}
+	b.failWith(func(string, string, string) error {
+		if b.locked {
+			return dbus.Error{Name: "org.freedesktop.Secret.Error.IsLocked"}
+		}
+		return nil
+	})
+	return b
+}
+
+func (b *lockableBackend) unlock(ctx context.Context, opts UnlockOptions) error {
+	if opts.Password != nil {
+		password, err := opts.Password(ctx)
+		if err != nil {
+			return err
+		}
+		if string(password) != b.password {
+			return dbus.Error{Name: "org.freedesktop.DBus.Error.AccessDenied"}
+		}
+	} else if opts.OnPrompt != nil {
+		if err := opts.OnPrompt(ctx); err != nil {
+			return err
+		}
+	}
+	b.locked = false
+	return nil
+}
+
+func (b *lockableBackend) lock(context.Context) error {
+	b.locked = true
+	return nil
+}
+
+func TestProvider_Unlock(t *testing.T) {
+	ctx := context.Background()
+	b := newLockableBackend("hunter2")
+	useBackend(t, b)
+
+	p := New(Config{ServiceName: "test-unlock"})
+	defer p.Close()
+
+	if err := p.Set(ctx, "key", &vault.Secret{Value: "v"}); !errors.Is(err, ErrKeyringLocked) {
+		t.Fatalf("expected ErrKeyringLocked before unlock, got %v", err)
+	}
+
+	err := p.Unlock(ctx, UnlockOptions{Password: func(context.Context) ([]byte, error) {
+		return []byte("wrong"), nil
+	}})
+	if !errors.Is(err, vault.ErrAccessDenied) {
+		t.Errorf("expected ErrAccessDenied for a wrong password, got %v", err)
+	}
+
+	err = p.Unlock(ctx, UnlockOptions{Password: func(context.Context) ([]byte, error) {
+		return []byte("hunter2"), nil
+	}})
+	if err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+	if err := p.Set(ctx, "key", &vault.Secret{Value: "v"}); err != nil {
+		t.Errorf("expected Set to succeed after unlock, got %v", err)
+	}
+
+	if err := p.Lock(ctx); err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	if _, err := p.Get(ctx, "key"); !errors.Is(err, ErrKeyringLocked) {
+		t.Errorf("expected ErrKeyringLocked after lock, got %v", err)
+	}
+}
+
+func TestProvider_Unlock_PromptRefused(t *testing.T) {
+	b := newLockableBackend("")
+	useBackend(t, b)
+
+	p := New(Config{ServiceName: "test-unlock-prompt"})
+	defer p.Close()
+
+	errHeadless := errors.New("no user to answer the prompt")
+	err := p.Unlock(context.Background(), UnlockOptions{
+		OnPrompt: func(context.Context) error { return errHeadless },
+	})
+	if !errors.Is(err, errHeadless) {
+		t.Errorf("expected the prompt callback's error, got %v", err)
+	}
+	if !b.locked {
+		t.Error("expected the collection to stay locked")
+	}
+}
+
+func TestProvider_Unlock_NotSupported(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+	p := New(Config{ServiceName: "test-unlock-unsupported"})
+
+	if err := p.Unlock(context.Background(), UnlockOptions{}); !errors.Is(err, vault.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported from Unlock, got %v", err)
+	}
+	if err := p.Lock(context.Background()); !errors.Is(err, vault.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported from Lock, got %v", err)
+	}
+
+	_ = p.Close()
+	if err := p.Unlock(context.Background(), UnlockOptions{}); !errors.Is(err, vault.ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
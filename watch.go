@@ -0,0 +1,226 @@
+package keyring
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/agentplexus/omnivault/vault"
+	zkeyring "github.com/zalando/go-keyring"
+)
+
+// DefaultWatchInterval is the default polling interval used by Watch when
+// the backend can't deliver change notifications natively.
+const DefaultWatchInterval = 5 * time.Second
+
+// EventType identifies the kind of change reported by Watch.
+type EventType int
+
+const (
+	// EventCreated reports a secret stored at a previously unused path.
+	EventCreated EventType = iota + 1
+
+	// EventUpdated reports a change to an existing secret.
+	EventUpdated
+
+	// EventDeleted reports the removal of a secret.
+	EventDeleted
+)
+
+// String returns the name of the event type.
+func (t EventType) String() string {
+	switch t {
+	case EventCreated:
+		return "created"
+	case EventUpdated:
+		return "updated"
+	case EventDeleted:
+		return "deleted"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a change to a secret observed by Watch.
+type Event struct {
+	// Type is the kind of change.
+	Type EventType
+
+	// Path is the path of the secret that changed.
+	Path string
+}
+
+// eventSource is implemented by backends that can push change notifications,
+// such as the Secret Service on Linux.
+type eventSource interface {
+	// subscribe starts delivering changes to secrets under service to emit
+	// until ctx is done, and returns a channel that is closed once delivery
+	// has stopped. It returns an error if notifications are unavailable.
+	subscribe(ctx context.Context, service string, emit func(user string, typ EventType)) (<-chan struct{}, error)
+}
+
+// Watch returns a channel of changes to secrets whose paths start with prefix,
+// including changes made by other processes.
+//
+// Events come from native backend notifications where available (Secret
+// Service ItemCreated, ItemChanged and ItemDeleted signals on Linux), and
+// otherwise from polling the indexed secrets every Config.WatchInterval and
+// comparing content hashes. Polling only sees secrets recorded in the index.
+//
+// The channel is closed when ctx is done or the provider is closed.
+func (p *Provider) Watch(ctx context.Context, prefix string) (<-chan Event, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return nil, vault.NewVaultError("Watch", prefix, p.Name(), vault.ErrClosed)
+	}
+	prefix, err := p.canonicalPrefix("Watch", prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan Event, 64)
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		// Stop watching when the provider is closed.
+		select {
+		case <-ctx.Done():
+		case <-p.done:
+			cancel()
+		}
+	}()
+
+	// Native notifications carry backend keys, which can't be mapped back
+	// to paths when they are obfuscated.
+	if src, ok := p.backend.(eventSource); ok && p.paths == nil {
+		emit := func(user string, typ EventType) {
+			user, ok := strings.CutPrefix(user, p.keyPrefix)
+			if !ok || isReservedKey(user) || !strings.HasPrefix(user, prefix) {
+				return
+			}
+			select {
+			case events <- Event{Type: typ, Path: user}:
+			case <-ctx.Done():
+			}
+		}
+		if stopped, err := src.subscribe(ctx, p.config.ServiceName, emit); err == nil {
+			go func() {
+				<-stopped
+				close(events)
+			}()
+			return events, nil
+		}
+		// Fall back to polling
+	}
+
+	snapshot, err := p.snapshot(ctx, prefix, nil)
+	if err != nil {
+		cancel()
+		return nil, vault.NewVaultError("Watch", prefix, p.Name(), err)
+	}
+	go p.poll(ctx, prefix, snapshot, events)
+	return events, nil
+}
+
+// poll diffs content hashes of indexed secrets under prefix on every tick
+// and sends the differences to events.
+func (p *Provider) poll(ctx context.Context, prefix string, last map[string]string, events chan<- Event) {
+	defer close(events)
+
+	ticker := time.NewTicker(p.config.WatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.RLock()
+		if p.closed {
+			p.mu.RUnlock()
+			return
+		}
+		current, err := p.snapshot(ctx, prefix, last)
+		p.mu.RUnlock()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			continue // Timed out; try again on the next tick
+		}
+
+		for _, ev := range diffSnapshots(last, current) {
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+		last = current
+	}
+}
+
+// snapshot returns the ETags of all indexed secrets under prefix.
+// Secrets that can't be read keep their hash from prev, so transient backend
+// errors don't surface as spurious events. It returns an error, and no
+// snapshot, if it didn't finish before ctx or Config.DefaultTimeout expired.
+// The caller must hold p.mu.
+func (p *Provider) snapshot(ctx context.Context, prefix string, prev map[string]string) (map[string]string, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	snap := make(map[string]string)
+	for _, key := range p.loadIndex(ctx) {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		value, err := p.read(ctx, key)
+		switch {
+		case err == nil:
+			snap[key] = valueDigest(value)
+		case !errors.Is(err, zkeyring.ErrNotFound):
+			if hash, ok := prev[key]; ok {
+				snap[key] = hash
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// valueDigest returns a hash of a stored value, for spotting changes. It
+// never leaves the process, unlike an ETag.
+func valueDigest(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:16])
+}
+
+// diffSnapshots returns the events that turn prev into next, sorted by path.
+func diffSnapshots(prev, next map[string]string) []Event {
+	var events []Event
+	for path, hash := range next {
+		old, ok := prev[path]
+		switch {
+		case !ok:
+			events = append(events, Event{Type: EventCreated, Path: path})
+		case old != hash:
+			events = append(events, Event{Type: EventUpdated, Path: path})
+		}
+	}
+	for path := range prev {
+		if _, ok := next[path]; !ok {
+			events = append(events, Event{Type: EventDeleted, Path: path})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Path < events[j].Path })
+	return events
+}
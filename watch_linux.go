@@ -0,0 +1,146 @@
+//go:build linux
+
+package keyring
+
+import (
+	"context"
+	"sync"
+
+	dbus "github.com/godbus/dbus/v5"
+)
+
+const (
+	secretServiceName     = "org.freedesktop.secrets"
+	secretServicePath     = "/org/freedesktop/secrets"
+	secretServiceIface    = "org.freedesktop.Secret.Service"
+	secretCollectionIface = "org.freedesktop.Secret.Collection"
+	secretItemIface       = "org.freedesktop.Secret.Item"
+)
+
+// subscribe delivers Secret Service ItemCreated, ItemChanged and ItemDeleted
+// signals for items belonging to service.
+//
+// It uses a private D-Bus connection so the signal subscription doesn't
+// interfere with the prompt handling in go-keyring, which reads signals from
+// the shared session bus connection.
+func (osBackend) subscribe(ctx context.Context, service string, emit func(user string, typ EventType)) (<-chan struct{}, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+
+	match := []dbus.MatchOption{
+		dbus.WithMatchSender(secretServiceName),
+		dbus.WithMatchInterface(secretCollectionIface),
+	}
+	if err := conn.AddMatchSignalContext(ctx, match...); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	// Item paths are opaque, so remember which user each item belongs to;
+	// deleted items can no longer be asked for their attributes.
+	items := &itemUsers{users: make(map[dbus.ObjectPath]string)}
+	if err := items.load(ctx, conn, service); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		defer conn.Close()
+		defer conn.RemoveSignal(signals)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig, ok := <-signals:
+				if !ok {
+					return
+				}
+				if len(sig.Body) == 0 {
+					continue
+				}
+				item, ok := sig.Body[0].(dbus.ObjectPath)
+				if !ok {
+					continue
+				}
+				switch sig.Name {
+				case secretCollectionIface + ".ItemCreated":
+					if user, ok := items.lookup(conn, service, item); ok {
+						emit(user, EventCreated)
+					}
+				case secretCollectionIface + ".ItemChanged":
+					if user, ok := items.lookup(conn, service, item); ok {
+						emit(user, EventUpdated)
+					}
+				case secretCollectionIface + ".ItemDeleted":
+					if user, ok := items.forget(item); ok {
+						emit(user, EventDeleted)
+					}
+				}
+			}
+		}
+	}()
+
+	return stopped, nil
+}
+
+// itemUsers maps Secret Service item paths to go-keyring user names.
+type itemUsers struct {
+	mu    sync.Mutex
+	users map[dbus.ObjectPath]string
+}
+
+// load records every existing item belonging to service.
+func (m *itemUsers) load(ctx context.Context, conn *dbus.Conn, service string) error {
+	var paths []dbus.ObjectPath
+	var locked []dbus.ObjectPath
+	err := conn.Object(secretServiceName, secretServicePath).CallWithContext(ctx,
+		secretServiceIface+".SearchItems", 0, map[string]string{"service": service}).
+		Store(&paths, &locked)
+	if err != nil {
+		return err
+	}
+	for _, item := range append(paths, locked...) {
+		m.lookup(conn, service, item)
+	}
+	return nil
+}
+
+// lookup returns the user name of item if it belongs to service, reading
+// and caching its attributes.
+func (m *itemUsers) lookup(conn *dbus.Conn, service string, item dbus.ObjectPath) (string, bool) {
+	var attrs map[string]string
+	v, err := conn.Object(secretServiceName, item).GetProperty(secretItemIface + ".Attributes")
+	if err == nil {
+		err = v.Store(&attrs)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		// Fall back to what we already know about the item.
+		user, ok := m.users[item]
+		return user, ok
+	}
+	if attrs["service"] != service {
+		return "", false
+	}
+	m.users[item] = attrs["username"]
+	return attrs["username"], true
+}
+
+// forget removes item and returns the user name it belonged to.
+func (m *itemUsers) forget(item dbus.ObjectPath) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[item]
+	delete(m.users, item)
+	return user, ok
+}
@@ -0,0 +1,148 @@
+package keyring
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/agentplexus/omnivault/vault"
+)
+
+// nextEvent waits for an event or fails the test.
+func nextEvent(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatal("event channel closed unexpectedly")
+		}
+		return ev
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return Event{}
+}
+
+func TestProvider_Watch_Polling(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	useBackend(t, newMemoryBackend())
+
+	p := New(Config{ServiceName: "test-watch", WatchInterval: 10 * time.Millisecond})
+	defer p.Close()
+
+	_ = p.Set(ctx, "app/existing", &vault.Secret{Value: "1"})
+	_ = p.Set(ctx, "other/ignored", &vault.Secret{Value: "1"})
+
+	events, err := p.Watch(ctx, "app/")
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	_ = p.Set(ctx, "app/new", &vault.Secret{Value: "1"})
+	if ev := nextEvent(t, events); ev.Type != EventCreated || ev.Path != "app/new" {
+		t.Errorf("expected created app/new, got %v %s", ev.Type, ev.Path)
+	}
+
+	_ = p.Set(ctx, "other/ignored", &vault.Secret{Value: "2"})
+	_ = p.Set(ctx, "app/existing", &vault.Secret{Value: "2"})
+	if ev := nextEvent(t, events); ev.Type != EventUpdated || ev.Path != "app/existing" {
+		t.Errorf("expected updated app/existing, got %v %s", ev.Type, ev.Path)
+	}
+
+	_ = p.Delete(ctx, "app/new")
+	if ev := nextEvent(t, events); ev.Type != EventDeleted || ev.Path != "app/new" {
+		t.Errorf("expected deleted app/new, got %v %s", ev.Type, ev.Path)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("expected no further events after cancel")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected channel to close after cancel")
+	}
+}
+
+func TestProvider_Watch_ClosedProvider(t *testing.T) {
+	useBackend(t, newMemoryBackend())
+	p := New(Config{ServiceName: "test-watch-close", WatchInterval: 10 * time.Millisecond})
+
+	events, err := p.Watch(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	_ = p.Close()
+
+	select {
+	case <-events:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected channel to close when provider is closed")
+	}
+
+	if _, err := p.Watch(context.Background(), ""); err == nil {
+		t.Error("expected error watching a closed provider")
+	}
+}
+
+// signalBackend is a memory backend that pushes change notifications.
+type signalBackend struct {
+	*memoryBackend
+	emit chan func(user string, typ EventType)
+}
+
+func (b *signalBackend) subscribe(ctx context.Context, _ string, emit func(string, EventType)) (<-chan struct{}, error) {
+	b.emit <- emit
+	stopped := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		close(stopped)
+	}()
+	return stopped, nil
+}
+
+func TestProvider_Watch_Native(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := &signalBackend{memoryBackend: newMemoryBackend(), emit: make(chan func(string, EventType), 1)}
+	useBackend(t, b)
+
+	p := New(Config{ServiceName: "test-watch-native", WatchInterval: time.Hour})
+	defer p.Close()
+
+	events, err := p.Watch(ctx, "app/")
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	emit := <-b.emit
+
+	emit(indexKey, EventUpdated)  // reserved, filtered
+	emit("other/x", EventCreated) // outside prefix, filtered
+	emit("app/x", EventCreated)
+
+	if ev := nextEvent(t, events); ev.Type != EventCreated || ev.Path != "app/x" {
+		t.Errorf("expected created app/x, got %v %s", ev.Type, ev.Path)
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	prev := map[string]string{"a": "1", "b": "2", "c": "3"}
+	next := map[string]string{"a": "1", "b": "changed", "d": "4"}
+
+	events := diffSnapshots(prev, next)
+	want := []Event{
+		{Type: EventUpdated, Path: "b"},
+		{Type: EventDeleted, Path: "c"},
+		{Type: EventCreated, Path: "d"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("expected %v, got %v", want, events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d: expected %v, got %v", i, want[i], events[i])
+		}
+	}
+}